@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// account_tree is built once from the accounts so is_father and the sums of the statements don't walk
+// the accounts on every call. the fathers of every account are saved as a set so is_father is one lookup
+type account_tree struct {
+	paths     map[string][]string        // the account and its fathers up to the one that has no father
+	ancestors map[string]map[string]bool // the account, its fathers and "" as is_father sees them
+	children  map[string][]string        // in the order of the accounts, "" has the accounts that have no father
+	codes     map[string]string          // the name of the account of every code
+	order     map[string]int             // the place of the account when they are sorted by code, the accounts without code are last
+}
+
+// new_account_tree returns an error if a father is not in the accounts or the fathers make a circle,
+// the tree is still returned in that case and every path stops before the account repeats
+func new_account_tree(accounts []account) (*account_tree, error) {
+	tree, violations := build_account_tree(accounts)
+	return tree, first_error(violations)
+}
+
+// build_account_tree is new_account_tree that reports every account that does not end in the empty father,
+// the accounts under them are not reported again
+func build_account_tree(accounts []account) (*account_tree, []violation) {
+	tree := &account_tree{map[string][]string{}, map[string]map[string]bool{}, map[string][]string{}, map[string]string{}, map[string]int{}}
+	fathers := map[string]string{}
+	for _, a := range accounts {
+		fathers[a.name] = a.father
+		tree.children[a.father] = append(tree.children[a.father], a.name)
+		if a.code != "" {
+			tree.codes[a.code] = a.name
+		}
+	}
+	sorted := append([]account{}, accounts...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].code == "" || sorted[j].code == "" {
+			return sorted[j].code == "" && sorted[i].code != ""
+		}
+		return sorted[i].code < sorted[j].code
+	})
+	for index, a := range sorted {
+		tree.order[a.name] = index
+	}
+	var violations []violation
+	for _, a := range accounts {
+		path := []string{a.name}
+		ancestors := map[string]bool{a.name: true}
+		for name := a.father; ; name = fathers[name] {
+			if name == "" {
+				ancestors[""] = true
+				break
+			}
+			if _, ok := fathers[name]; !ok {
+				if name != a.father {
+					break
+				}
+				violations = append(violations, violation{severity_error, a.name, 0,
+					fmt.Sprint(a.name, " account does not ends in '' because its father ", name, " is not in the accounts")})
+				break
+			}
+			if ancestors[name] {
+				if name != a.name {
+					break
+				}
+				violations = append(violations, violation{severity_error, a.name, 0,
+					fmt.Sprint(a.name, " account does not ends in '' because ", name, " is one of its fathers and one of its sons")})
+				break
+			}
+			path = append(path, name)
+			ancestors[name] = true
+		}
+		tree.paths[a.name] = path
+		tree.ancestors[a.name] = ancestors
+	}
+	return tree, violations
+}
+
+// chart_tree returns the tree that initialize built, or builds one if the accounts are not initialized yet
+func (s Financial_accounting) chart_tree() *account_tree {
+	if s.tree != nil {
+		return s.tree
+	}
+	tree, _ := new_account_tree(s.accounts)
+	return tree
+}
+
+// is_father is true if father is name itself or one of its fathers, and the empty father is the father of every account
+func (s Financial_accounting) is_father(father, name string) bool {
+	ancestors, ok := s.chart_tree().ancestors[name]
+	if !ok {
+		return father == name
+	}
+	return ancestors[father]
+}
+
+// account_path returns the fathers of the account from the top to the account itself
+func (s Financial_accounting) account_path(name string) ([]string, error) {
+	path, ok := s.chart_tree().paths[name]
+	if !ok {
+		return nil, ErrUnknownAccount{name}
+	}
+	reversed := make([]string, len(path))
+	for index, a := range path {
+		reversed[len(path)-1-index] = a
+	}
+	return reversed, nil
+}
+
+// account_depth is 0 for the accounts that have no father
+func (s Financial_accounting) account_depth(name string) (int, error) {
+	path, ok := s.chart_tree().paths[name]
+	if !ok {
+		return 0, ErrUnknownAccount{name}
+	}
+	return len(path) - 1, nil
+}
+
+// account_children returns the accounts that name is their father directly, use "" for the top accounts
+func (s Financial_accounting) account_children(name string) ([]string, error) {
+	tree := s.chart_tree()
+	if _, ok := tree.paths[name]; !ok && name != "" {
+		return nil, ErrUnknownAccount{name}
+	}
+	return append([]string{}, tree.children[name]...), nil
+}
+
+// account_leaves returns the accounts under name that have no children, or name itself if it has no children
+func (s Financial_accounting) account_leaves(name string) ([]string, error) {
+	tree := s.chart_tree()
+	if _, ok := tree.paths[name]; !ok && name != "" {
+		return nil, ErrUnknownAccount{name}
+	}
+	var leaves []string
+	visited := map[string]bool{}
+	var walk func(name string)
+	walk = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+		children := tree.children[name]
+		if len(children) == 0 {
+			if name != "" {
+				leaves = append(leaves, name)
+			}
+			return
+		}
+		for _, a := range children {
+			walk(a)
+		}
+	}
+	walk(name)
+	return leaves, nil
+}
+
+// account_of_code changes the codes in the entry to the names of their accounts, the names stay as they are
+func (s Financial_accounting) account_of_code(array_of_entry []Account_value_quantity_barcode) {
+	tree := s.chart_tree()
+	for index, entry := range array_of_entry {
+		if name, ok := tree.codes[entry.Account]; ok {
+			array_of_entry[index].Account = name
+		}
+	}
+}
+
+// sort_by_code sorts the statement by the codes of the accounts then by the keys, the keys that are not accounts are last
+func (s Financial_accounting) sort_by_code(statement []filtered_statement) {
+	tree := s.chart_tree()
+	order := func(name string) int {
+		if index, ok := tree.order[name]; ok {
+			return index
+		}
+		return len(tree.order)
+	}
+	sort.SliceStable(statement, func(i, j int) bool {
+		a, b := statement[i], statement[j]
+		switch {
+		case order(a.key_account) != order(b.key_account):
+			return order(a.key_account) < order(b.key_account)
+		case a.key_account != b.key_account:
+			return a.key_account < b.key_account
+		case order(a.key_account_flow) != order(b.key_account_flow):
+			return order(a.key_account_flow) < order(b.key_account_flow)
+		case a.key_account_flow != b.key_account_flow:
+			return a.key_account_flow < b.key_account_flow
+		case a.key_name != b.key_name:
+			return a.key_name < b.key_name
+		case a.key_vpq != b.key_vpq:
+			return a.key_vpq < b.key_vpq
+		}
+		return a.key_number < b.key_number
+	})
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// the fathers that make a circle are reported for every account in it and is_father still ends
+func TestAccountTreeCycle(t *testing.T) {
+	accounts := []account{
+		{false, "", "", "assets", false, "", account_metadata{}},
+		{false, "", "c", "a", false, "", account_metadata{}},
+		{false, "", "a", "b", false, "", account_metadata{}},
+		{false, "", "b", "c", false, "", account_metadata{}},
+		{false, "", "a", "d", false, "", account_metadata{}},
+	}
+	tree, violations := build_account_tree(accounts)
+	var cyclic []string
+	for _, v := range violations {
+		cyclic = append(cyclic, v.account)
+	}
+	if !reflect.DeepEqual(cyclic, []string{"a", "b", "c"}) {
+		t.Errorf("the accounts in the circle are %v not [a b c]", cyclic)
+	}
+	if _, err := new_account_tree(accounts); err == nil {
+		t.Error("new_account_tree should refuse the circle")
+	}
+	s := Financial_accounting{accounts: accounts, tree: tree}
+	if !s.is_father("a", "d") || s.is_father("", "d") || s.is_father("assets", "a") {
+		t.Error("is_father is wrong on the accounts in the circle")
+	}
+	if path, _ := s.account_path("d"); !reflect.DeepEqual(path, []string{"b", "c", "a", "d"}) {
+		t.Errorf("the path of d stops before the circle repeats, it is %v", path)
+	}
+	company := test_company(t, "memory", "")
+	company.accounts = append(company.accounts, accounts[1:]...)
+	if company.check_config() == nil {
+		t.Error("check_config should refuse the circle")
+	}
+}
+
+// the path, depth, fathers, children and leaves of a deep tree
+func TestDeepAccountTree(t *testing.T) {
+	const depth = 300
+	var accounts []account
+	for level := 0; level < depth; level++ {
+		father := ""
+		if level > 0 {
+			father = fmt.Sprint("level ", level-1)
+		}
+		accounts = append(accounts, account{false, "", father, fmt.Sprint("level ", level), false, "", account_metadata{}})
+	}
+	accounts = append(accounts, account{false, "", "level 150", "branch", false, "", account_metadata{}})
+	tree, err := new_account_tree(accounts)
+	check(t, err)
+	s := Financial_accounting{accounts: accounts, tree: tree}
+
+	path, err := s.account_path("level 299")
+	check(t, err)
+	if len(path) != depth || path[0] != "level 0" || path[depth-1] != "level 299" {
+		t.Errorf("the path of level 299 is %d accounts from %s to %s", len(path), path[0], path[len(path)-1])
+	}
+	if path, _ := s.account_path("branch"); strings.Join(path[149:], ",") != "level 149,level 150,branch" {
+		t.Errorf("the path of branch ends in %v", path[149:])
+	}
+	for name, expected := range map[string]int{"level 0": 0, "level 299": 299, "branch": 151} {
+		if d, _ := s.account_depth(name); d != expected {
+			t.Errorf("the depth of %s is %d not %d", name, d, expected)
+		}
+	}
+	if !s.is_father("level 0", "level 299") || !s.is_father("level 150", "branch") || s.is_father("level 151", "branch") || s.is_father("level 299", "level 0") {
+		t.Error("is_father is wrong in the deep tree")
+	}
+	if children, _ := s.account_children("level 150"); !reflect.DeepEqual(children, []string{"level 151", "branch"}) {
+		t.Errorf("the children of level 150 are %v", children)
+	}
+	if children, _ := s.account_children(""); !reflect.DeepEqual(children, []string{"level 0"}) {
+		t.Errorf("the top accounts are %v", children)
+	}
+	if leaves, _ := s.account_leaves("level 100"); !reflect.DeepEqual(leaves, []string{"level 299", "branch"}) {
+		t.Errorf("the leaves of level 100 are %v", leaves)
+	}
+	if leaves, _ := s.account_leaves("level 200"); !reflect.DeepEqual(leaves, []string{"level 299"}) {
+		t.Errorf("the leaves of level 200 are %v", leaves)
+	}
+	if _, err := s.account_path("nothing"); err != (ErrUnknownAccount{"nothing"}) {
+		t.Errorf("the path of an unknown account should be ErrUnknownAccount not %v", err)
+	}
+}
+
+// the codes in the entry are changed to the names of their accounts and journal_entry takes the code or the name
+func TestAccountOfCode(t *testing.T) {
+	s := test_company(t, "memory", "")
+	for name, code := range map[string]string{"cash": "1100", "capital": "3100", "rent": "5100"} {
+		s.accounts[index_of_account(s.accounts, name)].code = code
+	}
+	s.initialize()
+	entry := []Account_value_quantity_barcode{line("1100", 10, 10), line("capital", 10, 10), line("9999", 1, 1)}
+	s.account_of_code(entry)
+	if entry[0].Account != "cash" || entry[1].Account != "capital" || entry[2].Account != "9999" {
+		t.Errorf("account_of_code changed the entry to %v", entry)
+	}
+	post(t, s, test_day, line("1100", 1000, 1000), line("3100", 1000, 1000))
+	tags := post(t, s, test_day, line("5100", 60, 60), line("cash", -60, -60))
+	for _, tag := range tags {
+		if tag.account != "rent" && tag.account != "cash" {
+			t.Errorf("the journal has the code %s and not the name of its account", tag.account)
+		}
+	}
+	expect_balance(t, s, "cash", 940)
+	expect_balance(t, s, "rent", 60)
+	_, err := s.journal_entry([]Account_value_quantity_barcode{line("9999", 60, 60), line("cash", -60, -60)}, true, false, test_day, time.Time{}, "", "", "", "clerk", nil)
+	if _, ok := err.(ErrUnknownAccount); !ok {
+		t.Errorf("the code that is not of an account should be ErrUnknownAccount not %v", err)
+	}
+}
@@ -0,0 +1,599 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// the accounts in the config are saved in the database the first time only, after that the chart is read from
+// the database and changed with the methods below, every change is saved in account_changes
+
+// load_accounts saves the accounts of the config if the database has no accounts yet, else it reads them
+func (s *Financial_accounting) load_accounts() error {
+	return s.db.transaction(func(tx storage) error {
+		accounts, err := tx.accounts()
+		if err != nil {
+			return err
+		}
+		if len(accounts) != 0 {
+			s.accounts = accounts
+			return nil
+		}
+		for _, a := range s.accounts {
+			err = tx.insert_account(a)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// use_accounts builds the tree of the accounts and checks them, they are used only if they pass the checks
+func (s *Financial_accounting) use_accounts(accounts []account) error {
+	new_chart := *s
+	new_chart.accounts = accounts
+	var err error
+	new_chart.tree, err = new_account_tree(accounts)
+	if err != nil {
+		return err
+	}
+	new_chart.inventory_accounts, err = new_chart.check_chart()
+	if err != nil {
+		return err
+	}
+	s.accounts, s.tree, s.inventory_accounts = new_chart.accounts, new_chart.tree, new_chart.inventory_accounts
+	return nil
+}
+
+// check_chart checks the rules of the chart of accounts and returns the inventory accounts,
+// the tree should be built from the accounts before
+func (s Financial_accounting) check_chart() ([]string, error) {
+	inventory_accounts, violations := s.chart_violations()
+	err := first_error(violations)
+	if err != nil {
+		return nil, err
+	}
+	return inventory_accounts, nil
+}
+
+// chart_violations is check_chart that does not stop at the first problem
+func (s Financial_accounting) chart_violations() ([]string, []violation) {
+	var violations []violation
+	var all_accounts, inventory_accounts []string
+	for _, i := range s.accounts {
+		all_accounts = append(all_accounts, i.name)
+	}
+	for _, name := range duplicated_elements(all_accounts) {
+		violations = append(violations, violation{severity_error, name, 0, fmt.Sprint(name, " is duplicated in the accounts. you should remove the duplicate")})
+	}
+	var codes []string
+	for _, i := range s.accounts {
+		if i.code == "" {
+			continue
+		}
+		if s.is_account(i.code) {
+			violations = append(violations, violation{severity_error, i.name, 0, fmt.Sprint("the code ", i.code, " of ", i.name, " is the name of an account")})
+		}
+		codes = append(codes, i.code)
+	}
+	for _, code := range duplicated_elements(codes) {
+		violations = append(violations, violation{severity_error, s.chart_tree().codes[code], 0, fmt.Sprint("the code ", code, " is duplicated in the accounts")})
+	}
+	violations = append(violations, s.metadata_violations()...)
+	for _, i := range s.accounts {
+		switch {
+		case IS_IN(i.cost_flow_type, []string{"fifo", "lifo", "wma"}) && !s.is_father(s.retained_earnings, i.name) && !i.is_credit:
+			inventory_accounts = append(inventory_accounts, i.name)
+		case i.cost_flow_type == "":
+		default:
+			violations = append(violations, violation{severity_error, i.name, 0,
+				fmt.Sprint(i.cost_flow_type, " for ", i.name, " is not in [fifo,lifo,wma,''] or you can't use it with ", s.retained_earnings, " or is_credit==true")})
+		}
+	}
+
+	for _, a := range [][2]string{
+		{s.assets, s.current_assets},
+		{s.current_assets, s.cash_and_cash_equivalents},
+		{s.current_assets, s.short_term_investments},
+		{s.current_assets, s.receivables},
+		{s.current_assets, s.inventory},
+		{s.liabilities, s.current_liabilities},
+		{s.equity, s.retained_earnings},
+		{s.retained_earnings, s.dividends},
+		{s.retained_earnings, s.income_statement},
+		{s.income_statement, s.ebitda},
+		{s.income_statement, s.interest_expense},
+		{s.ebitda, s.sales},
+		{s.ebitda, s.cost_of_goods_sold},
+		{s.ebitda, s.discounts},
+		{s.discounts, s.invoice_discount},
+	} {
+		if !s.is_father(a[0], a[1]) {
+			violations = append(violations, violation{severity_error, a[1], 0, fmt.Sprint(a[0], " should be one of the fathers of ", a[1])})
+		}
+	}
+	return inventory_accounts, violations
+}
+
+// account_change is one row of the log of the chart, every change of the accounts is saved in it
+type account_change struct {
+	id                                       int
+	date                                     time.Time
+	operation, account, new_account, details string
+	employee_name                            string
+}
+
+// change_chart checks the accounts after f changes them, then saves them and uses them if they pass the checks.
+// f returns the change that is saved in the log with the new accounts, the config takes the new name of the renamed or merged account
+// before the checks so the special accounts can be renamed and merged too. the config is not in the database so when it has
+// the account it is saved to config_path before the transaction commits, the company that has no config_path can't rename it
+func (s *Financial_accounting) change_chart(employee_name string, f func(tx storage, accounts []account) ([]account, account_change, error)) error {
+	new_chart := *s
+	err := s.db.transaction(func(tx storage) error {
+		err := tx.lock_ledger()
+		if err != nil {
+			return err
+		}
+		accounts, change, err := f(tx, append([]account{}, s.accounts...))
+		if err != nil {
+			return err
+		}
+		var config_changed bool
+		if IS_IN(change.operation, []string{"update", "rename", "merge"}) && change.account != change.new_account {
+			config_changed = new_chart.rename_references(change.account, change.new_account)
+		}
+		if config_changed && new_chart.config_path == "" {
+			return ErrInvalidConfig{fmt.Sprint(change.account, " is in the config of the company and it has no file to save it, load it by load_config or change the config in the code")}
+		}
+		err = new_chart.use_accounts(accounts)
+		if err != nil {
+			return err
+		}
+		err = new_chart.check_accounts(tx, " is not have fifo lifo wma on cost_flow_type field", new_chart.inventory_accounts)
+		if err != nil {
+			return err
+		}
+		change.date, change.employee_name = Now, employee_name
+		err = tx.insert_account_change(change)
+		if err != nil || !config_changed {
+			return err
+		}
+		return new_chart.save_config(new_chart.config_path)
+	})
+	if err != nil {
+		return err
+	}
+	*s = new_chart
+	return nil
+}
+
+// rename_references changes name to new_name in the special accounts, auto_complete_entries, the lines of the transaction
+// templates and the approval rules and returns true if any of them had it
+func (s *Financial_accounting) rename_references(name, new_name string) bool {
+	var changed bool
+	for _, field := range []*string{&s.assets, &s.current_assets, &s.cash_and_cash_equivalents, &s.short_term_investments, &s.receivables, &s.inventory,
+		&s.liabilities, &s.current_liabilities, &s.equity, &s.retained_earnings, &s.dividends, &s.income_statement, &s.ebitda, &s.sales,
+		&s.cost_of_goods_sold, &s.discounts, &s.invoice_discount, &s.interest_expense, &s.exchange_difference} {
+		if *field == name {
+			*field = new_name
+			changed = true
+		}
+	}
+	// the slices are copied because the copies of the company share them
+	auto_complete_entries := make([][]account_method_value_price, len(s.auto_complete_entries))
+	for index, complement := range s.auto_complete_entries {
+		auto_complete_entries[index] = append([]account_method_value_price{}, complement...)
+		for i := range auto_complete_entries[index] {
+			if auto_complete_entries[index][i].account == name {
+				auto_complete_entries[index][i].account = new_name
+				changed = true
+			}
+		}
+	}
+	s.auto_complete_entries = auto_complete_entries
+	transaction_templates := append([]transaction_template{}, s.transaction_templates...)
+	for index, template := range transaction_templates {
+		// the account of the line can be the name of an account parameter
+		if template.parameter(name) != nil {
+			continue
+		}
+		transaction_templates[index].lines = append([]template_line{}, template.lines...)
+		for i := range transaction_templates[index].lines {
+			if transaction_templates[index].lines[i].account == name {
+				transaction_templates[index].lines[i].account = new_name
+				changed = true
+			}
+		}
+	}
+	s.transaction_templates = transaction_templates
+	approval_rules := append([]approval_rule{}, s.approval_rules...)
+	for index := range approval_rules {
+		if approval_rules[index].account == name {
+			approval_rules[index].account = new_name
+			changed = true
+		}
+	}
+	s.approval_rules = approval_rules
+	return changed
+}
+
+func (s Financial_accounting) account_changes() ([]account_change, error) {
+	return s.db.account_changes()
+}
+
+func (s *Financial_accounting) create_account(new_account account, employee_name string) error {
+	return s.change_chart(employee_name, func(tx storage, accounts []account) ([]account, account_change, error) {
+		if new_account.name == "" {
+			return nil, account_change{}, ErrInvalidConfig{"the name of the account can't be empty"}
+		}
+		change := account_change{operation: "create", new_account: new_account.name, details: describe_account(new_account)}
+		return append(accounts, new_account), change, tx.insert_account(new_account)
+	})
+}
+
+// update_account changes the account that is called name, if new_account has other name the journal, the inventory
+// and the sons of the account take the new name. is_credit can't be changed after the account is used
+func (s *Financial_accounting) update_account(name string, new_account account, employee_name string) error {
+	return s.change_account("update", name, new_account, employee_name)
+}
+
+func (s *Financial_accounting) change_account_name(name, new_name, employee_name string) error {
+	index := index_of_account(s.accounts, name)
+	if index == -1 {
+		return ErrUnknownAccount{name}
+	}
+	new_account := s.accounts[index]
+	new_account.name = new_name
+	return s.change_account("rename", name, new_account, employee_name)
+}
+
+// reparent_account moves the account and its sons under father, the journal don't change
+// because the statements sum the accounts to their fathers when they are made
+func (s *Financial_accounting) reparent_account(name, father, employee_name string) error {
+	index := index_of_account(s.accounts, name)
+	if index == -1 {
+		return ErrUnknownAccount{name}
+	}
+	new_account := s.accounts[index]
+	new_account.father = father
+	return s.change_account("reparent", name, new_account, employee_name)
+}
+
+// deactivate_account keeps the account and its entries in the statements but nothing can be posted to it after that
+func (s *Financial_accounting) deactivate_account(name, employee_name string) error {
+	index := index_of_account(s.accounts, name)
+	if index == -1 {
+		return ErrUnknownAccount{name}
+	}
+	new_account := s.accounts[index]
+	new_account.inactive = true
+	return s.change_account("deactivate", name, new_account, employee_name)
+}
+
+func (s *Financial_accounting) change_account(operation, name string, new_account account, employee_name string) error {
+	return s.change_chart(employee_name, func(tx storage, accounts []account) ([]account, account_change, error) {
+		index := index_of_account(accounts, name)
+		if index == -1 {
+			return nil, account_change{}, ErrUnknownAccount{name}
+		}
+		if new_account.name == "" {
+			return nil, account_change{}, ErrInvalidConfig{"the name of the account can't be empty"}
+		}
+		used, err := tx.is_account_used(name)
+		if err != nil {
+			return nil, account_change{}, err
+		}
+		if used && accounts[index].is_credit != new_account.is_credit {
+			return nil, account_change{}, ErrInvalidConfig{fmt.Sprint("you can't change is_credit of ", name, " because it is used in the journal")}
+		}
+		if new_account.name != name {
+			used, err = tx.is_account_used(new_account.name)
+			if err != nil {
+				return nil, account_change{}, err
+			}
+			if used || index_of_account(accounts, new_account.name) != -1 {
+				return nil, account_change{}, ErrInvalidConfig{fmt.Sprint("you can't change the name of [", name, "] to [", new_account.name, "] because it is used, use merge_accounts to join them")}
+			}
+			err = s.check_account_periods(tx, name, time.Time{}, employee_name)
+			if err != nil {
+				return nil, account_change{}, err
+			}
+			err = tx.rename_account(name, new_account.name)
+			if err != nil {
+				return nil, account_change{}, err
+			}
+			for i := range accounts {
+				if accounts[i].father == name {
+					accounts[i].father = new_account.name
+				}
+			}
+		}
+		change := account_change{operation: operation, account: name, new_account: new_account.name, details: describe_account_change(accounts[index], new_account)}
+		accounts[index] = new_account
+		return accounts, change, tx.update_account(name, new_account)
+	})
+}
+
+// merge_accounts moves all the entries and the inventory of name to into as if they were always entered in into,
+// then name is deleted and its sons become sons of into. both accounts should be on the same side and have the same cost_flow_type
+func (s *Financial_accounting) merge_accounts(name, into, employee_name string) error {
+	return s.change_chart(employee_name, func(tx storage, accounts []account) ([]account, account_change, error) {
+		from, to, err := s.check_merge(accounts, name, into)
+		if err != nil {
+			return nil, account_change{}, err
+		}
+		err = s.check_account_periods(tx, name, time.Time{}, employee_name)
+		if err != nil {
+			return nil, account_change{}, err
+		}
+		err = tx.rename_account(name, into)
+		if err != nil {
+			return nil, account_change{}, err
+		}
+		if to.cost_flow_type == "wma" {
+			err = tx.weighted_average(into)
+			if err != nil {
+				return nil, account_change{}, err
+			}
+		}
+		// the snapshots can have a row for each account with the same keys now so they are made again when they are needed
+		err = tx.delete_snapshots(time.Time{})
+		if err != nil {
+			return nil, account_change{}, err
+		}
+		var new_accounts []account
+		for _, a := range accounts {
+			switch {
+			case a.name == name:
+				continue
+			case a.father == name:
+				a.father = into
+				err = tx.update_account(a.name, a)
+				if err != nil {
+					return nil, account_change{}, err
+				}
+			}
+			new_accounts = append(new_accounts, a)
+		}
+		change := account_change{operation: "merge", account: name, new_account: into, details: describe_account(from)}
+		return new_accounts, change, tx.delete_account(name)
+	})
+}
+
+// reclassify_account posts an entry on date that moves the balance of name to into, then name is deactivated.
+// the entries before date stay in name so the old statements don't change. inventory accounts can't be reclassified
+// because their balance is in layers, use merge_accounts for them
+func (s *Financial_accounting) reclassify_account(name, into string, date time.Time, employee_name string) error {
+	return s.change_chart(employee_name, func(tx storage, accounts []account) ([]account, account_change, error) {
+		from, _, err := s.check_merge(accounts, name, into)
+		if err != nil {
+			return nil, account_change{}, err
+		}
+		if from.inactive {
+			return nil, account_change{}, ErrInactiveAccount{name}
+		}
+		if IS_IN(name, s.inventory_accounts) {
+			return nil, account_change{}, ErrInvalidConfig{fmt.Sprint(name, " is an inventory account and it can't be reclassified, use merge_accounts")}
+		}
+		journal, err := tx.journal_of_account(name)
+		if err != nil {
+			return nil, account_change{}, err
+		}
+		var value, quantity decimal.Decimal
+		for _, tag := range journal {
+			if !tag.date.Before(date) {
+				return nil, account_change{}, ErrInvalidConfig{fmt.Sprint(name, " has entries on or after ", date, " so its balance can't be reclassified on that date")}
+			}
+			value, quantity = value.Add(tag.value), quantity.Add(tag.quantity)
+		}
+		if !value.IsZero() || !quantity.IsZero() {
+			poster := *s
+			poster.db = tx
+			_, err = poster.post_entry([]Account_value_quantity_barcode{{name, value.Neg(), quantity.Neg(), ""}, {into, value, quantity, ""}},
+				true, false, date, time.Time{}, "", fmt.Sprint("to reclassify ", name, " to ", into), "", employee_name, nil, false)
+			if err != nil {
+				return nil, account_change{}, err
+			}
+		}
+		index := index_of_account(accounts, name)
+		accounts[index].inactive = true
+		change := account_change{operation: "reclassify", account: name, new_account: into, details: fmt.Sprint("value: ", value, ", quantity: ", quantity, ", date: ", date)}
+		return accounts, change, tx.update_account(name, accounts[index])
+	})
+}
+
+// split_account makes new_account and moves the entries of name on or after date to it, the entries before date stay in name.
+// new_account should be on the same side of name. inventory accounts can't be split because the layers are shared between the dates
+func (s *Financial_accounting) split_account(name string, new_account account, date time.Time, employee_name string) error {
+	return s.change_chart(employee_name, func(tx storage, accounts []account) ([]account, account_change, error) {
+		index := index_of_account(accounts, name)
+		if index == -1 {
+			return nil, account_change{}, ErrUnknownAccount{name}
+		}
+		if IS_IN(name, s.inventory_accounts) || new_account.cost_flow_type != "" {
+			return nil, account_change{}, ErrInvalidConfig{fmt.Sprint("inventory accounts can't be split, ", name, " or ", new_account.name, " has a cost_flow_type")}
+		}
+		if accounts[index].is_credit != new_account.is_credit {
+			return nil, account_change{}, ErrInvalidConfig{fmt.Sprint(new_account.name, " should have the same is_credit of ", name)}
+		}
+		if new_account.name == "" || index_of_account(accounts, new_account.name) != -1 {
+			return nil, account_change{}, ErrInvalidConfig{fmt.Sprint("the name [", new_account.name, "] is empty or used")}
+		}
+		err := s.check_account_periods(tx, name, date, employee_name)
+		if err != nil {
+			return nil, account_change{}, err
+		}
+		err = tx.insert_account(new_account)
+		if err != nil {
+			return nil, account_change{}, err
+		}
+		err = tx.move_journal(name, new_account.name, date)
+		if err != nil {
+			return nil, account_change{}, err
+		}
+		err = tx.delete_snapshots(date)
+		if err != nil {
+			return nil, account_change{}, err
+		}
+		change := account_change{operation: "split", account: name, new_account: new_account.name, details: fmt.Sprint(describe_account(new_account), ", date: ", date)}
+		return append(accounts, new_account), change, nil
+	})
+}
+
+// check_merge returns the accounts that are called name and into if name can be moved to into
+func (s Financial_accounting) check_merge(accounts []account, name, into string) (account, account, error) {
+	from_index, into_index := index_of_account(accounts, name), index_of_account(accounts, into)
+	switch {
+	case from_index == -1:
+		return account{}, account{}, ErrUnknownAccount{name}
+	case into_index == -1:
+		return account{}, account{}, ErrUnknownAccount{into}
+	case name == into:
+		return account{}, account{}, ErrInvalidConfig{fmt.Sprint("you can't move ", name, " to itself")}
+	case s.is_father(name, into):
+		return account{}, account{}, ErrInvalidConfig{fmt.Sprint("you can't move ", name, " to ", into, " because ", name, " is one of its fathers")}
+	}
+	from, to := accounts[from_index], accounts[into_index]
+	if from.is_credit != to.is_credit || from.cost_flow_type != to.cost_flow_type {
+		return account{}, account{}, ErrInvalidConfig{fmt.Sprint(name, " and ", into, " should have the same is_credit and cost_flow_type")}
+	}
+	if to.inactive {
+		return account{}, account{}, ErrInactiveAccount{into}
+	}
+	return from, to, nil
+}
+
+func describe_account(a account) string {
+	return fmt.Sprint("code: ", a.code, ", father: ", a.father, ", is_credit: ", a.is_credit, ", cost_flow_type: ", a.cost_flow_type, ", inactive: ", a.inactive,
+		", contra: ", a.contra, ", cash_flow_category: ", a.cash_flow_category, ", currency: ", a.currency, ", tax_category: ", a.tax_category, ", allow_negative: ", a.allow_negative)
+}
+
+// describe_account_change writes the fields that changed only
+func describe_account_change(old, new account) string {
+	var changes []string
+	for _, a := range [][3]interface{}{
+		{"name", old.name, new.name},
+		{"code", old.code, new.code},
+		{"father", old.father, new.father},
+		{"is_credit", old.is_credit, new.is_credit},
+		{"cost_flow_type", old.cost_flow_type, new.cost_flow_type},
+		{"inactive", old.inactive, new.inactive},
+		{"contra", old.contra, new.contra},
+		{"cash_flow_category", old.cash_flow_category, new.cash_flow_category},
+		{"currency", old.currency, new.currency},
+		{"tax_category", old.tax_category, new.tax_category},
+		{"allow_negative", old.allow_negative, new.allow_negative},
+	} {
+		if a[1] != a[2] {
+			changes = append(changes, fmt.Sprint(a[0], ": ", a[1], " -> ", a[2]))
+		}
+	}
+	return strings.Join(changes, ", ")
+}
+
+// metadata_violations checks that the contra accounts are on the other side of their fathers,
+// and that the cash flow category is set on one account only in every branch and not on the cash itself
+func (s Financial_accounting) metadata_violations() []violation {
+	var violations []violation
+	for _, a := range s.accounts {
+		// the unknown father is reported by the violations of the tree
+		father_is_credit, err := s.is_credit(a.father)
+		if a.contra && (a.father == "" || (err == nil && father_is_credit == a.is_credit)) {
+			violations = append(violations, violation{severity_error, a.name, 0, fmt.Sprint(a.name, " is contra so it should have a father and is_credit should be the opposite of its father")})
+		}
+		if a.currency != "" && !is_currency_code(a.currency) {
+			violations = append(violations, violation{severity_error, a.name, 0, fmt.Sprint("the currency ", a.currency, " of ", a.name, " should be three capital letters like USD")})
+		}
+		violations = append(violations, s.tax_category_violations(a)...)
+		if a.cash_flow_category == "" {
+			continue
+		}
+		if !IS_IN(a.cash_flow_category, cash_flow_categories[:]) {
+			violations = append(violations, violation{severity_error, a.name, 0, fmt.Sprint("the cash_flow_category ", a.cash_flow_category, " of ", a.name, " is not in ", cash_flow_categories)})
+			continue
+		}
+		if s.is_father(s.cash_and_cash_equivalents, a.name) {
+			violations = append(violations, violation{severity_error, a.name, 0, fmt.Sprint(a.name, " is one of ", s.cash_and_cash_equivalents, " and it can't have a cash_flow_category")})
+			continue
+		}
+		for _, father := range s.chart_tree().paths[a.name][1:] {
+			if s.accounts[index_of_account(s.accounts, father)].cash_flow_category != "" {
+				violations = append(violations, violation{severity_error, a.name, 0, fmt.Sprint(a.name, " can't have a cash_flow_category because its father ", father, " has one")})
+				break
+			}
+		}
+	}
+	return violations
+}
+
+// tax_category_violations checks that the accounts of a tax category are on one side so their balances can be summed,
+// and that the fathers of the account have no category because its sons are in its category
+func (s Financial_accounting) tax_category_violations(a account) []violation {
+	if a.tax_category == "" {
+		return nil
+	}
+	var violations []violation
+	for _, b := range s.accounts {
+		if b.tax_category == a.tax_category && b.is_credit != a.is_credit {
+			violations = append(violations, violation{severity_error, a.name, 0, fmt.Sprint(a.name, " and ", b.name, " are in the tax_category ", a.tax_category, " and they should be on the same side")})
+			break
+		}
+	}
+	path := s.chart_tree().paths[a.name]
+	if len(path) == 0 {
+		return violations
+	}
+	for _, father := range path[1:] {
+		if index := index_of_account(s.accounts, father); index != -1 && s.accounts[index].tax_category != "" {
+			violations = append(violations, violation{severity_error, a.name, 0, fmt.Sprint(a.name, " can't have a tax_category because its father ", father, " has one")})
+			break
+		}
+	}
+	return violations
+}
+
+func is_currency_code(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// can_be_negative is true for the accounts that allow it and for the accounts of the equity that are on the credit side
+// like retained_earnings, the contra accounts of the equity can't be negative
+func (s Financial_accounting) can_be_negative(name string) bool {
+	index := index_of_account(s.accounts, name)
+	if index == -1 {
+		return false
+	}
+	a := s.accounts[index]
+	return a.allow_negative || (s.is_father(s.equity, name) && a.is_credit && !a.contra)
+}
+
+func (s Financial_accounting) is_contra(name string) bool {
+	index := index_of_account(s.accounts, name)
+	return index != -1 && s.accounts[index].contra
+}
+
+func (s Financial_accounting) is_inactive(name string) bool {
+	index := index_of_account(s.accounts, name)
+	return index != -1 && s.accounts[index].inactive
+}
+
+func index_of_account(accounts []account, name string) int {
+	for index, a := range accounts {
+		if a.name == name {
+			return index
+		}
+	}
+	return -1
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// contra_company is test_company with equipment and its accumulated depreciation, tax is in the vat category
+func contra_company(t *testing.T) Financial_accounting {
+	t.Helper()
+	s := test_company(t, "memory", "")
+	s.accounts = append(s.accounts,
+		account{false, "", "assets", "equipment", false, "", account_metadata{}},
+		account{true, "", "equipment", "accumulated depreciation", false, "", account_metadata{contra: true}},
+		account{false, "", "expenses", "depreciation", false, "", account_metadata{}})
+	s.accounts[index_of_account(s.accounts, "tax")].tax_category = "vat"
+	return s
+}
+
+// the contra account can't be more than its father and it is a deduction in the statement
+func TestContraAccounts(t *testing.T) {
+	s := contra_company(t)
+	s.initialize()
+	post(t, s, test_day, line("cash", 1000, 1000), line("capital", 1000, 1000))
+	post(t, s, test_day, line("equipment", 1000, 1000), line("cash", -1000, -1000))
+	post(t, s, test_day.AddDate(0, 0, 1), line("depreciation", 300, 300), line("accumulated depreciation", 300, 300))
+	_, err := s.journal_entry([]Account_value_quantity_barcode{line("depreciation", 800, 800), line("accumulated depreciation", 800, 800)}, true, false, test_day.AddDate(0, 0, 2), time.Time{}, "", "", "", "clerk", nil)
+	if e, ok := err.(ErrNegativeBalance); !ok || e.Account != "equipment" {
+		t.Errorf("the accumulated depreciation that is more than the equipment should be ErrNegativeBalance of equipment not %v", err)
+	}
+	statements, _, _, err := s.financial_statements(test_day.AddDate(0, 0, -1), test_day.AddDate(0, 1, 0), 1, nil, false)
+	check(t, err)
+	presented := statements[0]["financial_statement"]
+	if balance := presented["accumulated depreciation"]["all"]["value"]["ending_balance"]; !balance.Equal(number(-300)) {
+		t.Errorf("the accumulated depreciation is %s in the statement not -300", balance)
+	}
+	if balance := presented["equipment"]["all"]["value"]["ending_balance"]; !balance.Equal(number(700)) {
+		t.Errorf("the equipment is %s in the statement not 700", balance)
+	}
+}
+
+// the balances of the accounts are summed by their tax category and the sons can't have another category
+func TestTaxCategory(t *testing.T) {
+	s := contra_company(t)
+	s.initialize()
+	post(t, s, test_day, line("cash", 1000, 1000), line("capital", 1000, 1000))
+	post(t, s, test_day, line("cash", 50, 50), line("tax", 50, 50))
+	statements, _, _, err := s.financial_statements(test_day.AddDate(0, 0, -1), test_day.AddDate(0, 1, 0), 1, nil, false)
+	check(t, err)
+	if vat := statements[0]["tax_category"]["vat"]["all"]["value"]["ending_balance"]; !vat.Equal(number(50)) {
+		t.Errorf("the vat in the statement is %s not 50", vat)
+	}
+	s.accounts[index_of_account(s.accounts, "current_liabilities")].tax_category = "vat"
+	if s.check_config() == nil {
+		t.Error("tax can't have a tax_category when its father has one")
+	}
+}
+
+// rename and merge change the accounts in the special accounts, the templates, auto_complete_entries and the approval rules,
+// the config is saved to its file so the company starts again from it with the new names
+func TestRenameReferences(t *testing.T) {
+	s := test_company(t, "sqlite3", "")
+	s.transaction_templates = []transaction_template{{"rent", nil, []template_line{{"rent", "", "fixed", "60", "", ""}, {"cash", "", "fixed", "-60", "", ""}}}}
+	s.auto_complete_entries = [][]account_method_value_price{{{"rent", "copy", number(1), number(1)}, {"tax", "value", number(1), number(1)}}}
+	s.approval_rules = []approval_rule{{"rent", number(1000), nil}}
+	path := t.TempDir() + "/company.yaml"
+	check(t, s.save_config(path))
+	s, err := load_config(path)
+	check(t, err)
+	s.initialize()
+	check(t, s.change_account_name("rent", "office rent", "boss"))
+	check(t, s.merge_accounts("interest_expense", "office rent", "boss"))
+	for _, company := range []string{"renamed", "restarted"} {
+		if company == "restarted" {
+			s, err = load_config(path)
+			check(t, err)
+			s.initialize()
+		}
+		if s.interest_expense != "office rent" {
+			t.Errorf("%s: interest_expense is %s not office rent", company, s.interest_expense)
+		}
+		if s.transaction_templates[0].lines[0].account != "office rent" || s.auto_complete_entries[0][0].account != "office rent" || s.approval_rules[0].account != "office rent" {
+			t.Errorf("%s: the config still has rent %v %v %v", company, s.transaction_templates, s.auto_complete_entries, s.approval_rules)
+		}
+		check(t, s.check_config())
+	}
+	post(t, s, test_day, line("cash", 1000, 1000), line("capital", 1000, 1000))
+	_, err = s.template_entry("rent", nil, true, test_day, "", "", "clerk")
+	check(t, err)
+	expect_balance(t, s, "office rent", 60)
+}
+
+// the account that is in the config can't be renamed if the config has no file because the new name would be lost
+func TestRenameReferencesWithoutConfigFile(t *testing.T) {
+	s := test_company(t, "memory", "")
+	s.initialize()
+	if _, ok := s.change_account_name("interest_expense", "interest", "boss").(ErrInvalidConfig); !ok {
+		t.Error("renaming a special account without a config file should be ErrInvalidConfig")
+	}
+	if s.interest_expense != "interest_expense" || s.accounts[index_of_account(s.accounts, "interest_expense")].name != "interest_expense" {
+		t.Errorf("the refused rename changed the company to %s", s.interest_expense)
+	}
+	check(t, s.change_account_name("rent", "office rent", "boss"))
+}
+
+// the accounts are created, changed and deactivated at runtime, the changes that break the rules of the chart are refused
+// and the chart is read from the database when the company starts again
+func TestChartChanges(t *testing.T) {
+	s := test_company(t, "sqlite3", "")
+	s.initialize()
+	check(t, s.create_account(account{false, "", "expenses", "salaries", false, "", account_metadata{}}, "boss"))
+	post(t, s, test_day, line("cash", 1000, 1000), line("capital", 1000, 1000))
+	post(t, s, test_day, line("salaries", 100, 100), line("cash", -100, -100))
+
+	if s.create_account(account{false, "fifo", "expenses", "goods", false, "", account_metadata{}}, "boss") == nil || s.is_account("goods") {
+		t.Error("the inventory account under retained_earnings is created")
+	}
+	if s.create_account(account{false, "", "expenses", "rent", false, "", account_metadata{}}, "boss") == nil {
+		t.Error("the duplicated account is created")
+	}
+	salaries := s.accounts[index_of_account(s.accounts, "salaries")]
+	salaries.is_credit = true
+	if s.update_account("salaries", salaries, "boss") == nil {
+		t.Error("is_credit of the used account is changed")
+	}
+	if s.reparent_account("salaries", "salaries", "boss") == nil {
+		t.Error("the account is its own father")
+	}
+	check(t, s.reparent_account("salaries", "ebitda", "boss"))
+	check(t, s.deactivate_account("salaries", "boss"))
+	_, err := s.journal_entry([]Account_value_quantity_barcode{line("salaries", 10, 10), line("cash", -10, -10)}, true, false, test_day, time.Time{}, "", "", "", "clerk", nil)
+	if _, ok := err.(ErrInactiveAccount); !ok {
+		t.Errorf("the entry of the deactivated account should be ErrInactiveAccount not %v", err)
+	}
+
+	restarted := test_company(t, "sqlite3", s.DataSourceName)
+	restarted.initialize()
+	salaries = restarted.accounts[index_of_account(restarted.accounts, "salaries")]
+	if salaries.father != "ebitda" || !salaries.inactive || restarted.is_account("goods") {
+		t.Errorf("the chart is not read from the database %v", salaries)
+	}
+	expect_balance(t, restarted, "salaries", 100)
+	changes, err := restarted.account_changes()
+	check(t, err)
+	if len(changes) != 3 || changes[0].operation != "create" || changes[1].operation != "reparent" || changes[2].operation != "deactivate" || changes[2].employee_name != "boss" {
+		t.Errorf("the changes of the chart are %v", changes)
+	}
+}
@@ -12,7 +12,7 @@ import (
 	"text/tabwriter"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/shopspring/decimal"
 )
 
 type day_start_end struct {
@@ -32,24 +32,40 @@ type day_start_end_date_minutes struct {
 
 type Account_value_quantity_barcode struct {
 	Account  string
-	value    float64
-	quantity float64
+	value    decimal.Decimal
+	quantity decimal.Decimal
 	barcode  string
 }
 
+// account_method_value_price is a line of auto_complete_entries, the first line of a group is the trigger and the others
+// are added by journal_entry with auto_completion to every entry that has the trigger. it is kept with the transaction
+// templates because it completes the entries that the clerks enter line by line, like the tax of every sale, while a
+// template makes the whole entry from its inputs and is used only when it is named
 type account_method_value_price struct {
 	account, method         string
-	value_or_percent, price float64
+	value_or_percent, price decimal.Decimal
 }
 
 type invoice_struct struct {
 	account                string
-	value, price, quantity float64
+	value, price, quantity decimal.Decimal
 }
 
 type account struct {
 	is_credit                    bool
 	cost_flow_type, father, name string
+	inactive                     bool
+	code                         string // optional, journal_entry takes it in place of the name and the statements are sorted by it
+	account_metadata
+}
+
+// account_metadata is optional, the zero value is an account on the side of is_credit that can't be negative
+type account_metadata struct {
+	contra             bool   // on the other side of its father like accumulated depreciation under assets
+	cash_flow_category string // operating, investing or financing, the sons of the account are in the category too
+	currency           string // the ISO 4217 code, empty is the currency of the company
+	tax_category       string // the statements sum the balances of the accounts by it, the sons of the account are in the category too
+	allow_negative     bool
 }
 
 type Financial_accounting struct {
@@ -73,54 +89,77 @@ type Financial_accounting struct {
 	discounts                                 string
 	invoice_discount                          string
 	interest_expense                          string
+	exchange_difference                       string // the unrealized gain or loss of revalue_currencies, it can be empty if there is no revaluation
 	accounts                                  []account
-	Invoice_discounts_list                    [][2]float64
+	Invoice_discounts_list                    [][2]decimal.Decimal
 	auto_complete_entries                     [][]account_method_value_price
+	transaction_templates                     []transaction_template
+	approval_rules                            []approval_rule
+	period_managers                           []string      // the employees that can close, lock and reopen the periods and post in the closed periods
+	value_places, quantity_places             int32         // the places the values and quantities are rounded to, both 0 means 2 and 6
+	config_path                               string        // the file that load_config read, change_chart saves the renamed accounts of the config to it
+	db                                        storage       // filled by initialize, every company has its own database
+	inventory_accounts                        []string      // filled by initialize from the accounts that have cost_flow_type
+	tree                                      *account_tree // filled by initialize from the accounts
 }
 
 type journal_tag struct {
-	date          string
+	id            int
+	date          time.Time
 	entry_number  int
 	account       string
-	value         float64
-	price         float64
-	quantity      float64
+	value         decimal.Decimal
+	price         decimal.Decimal
+	quantity      decimal.Decimal
 	barcode       string
-	entry_expair  string
+	entry_expair  time.Time
 	description   string
 	name          string
 	employee_name string
-	entry_date    string
+	entry_date    time.Time
 	reverse       bool
+	currency      string          // empty is the currency of the company
+	foreign_value decimal.Decimal // the value in currency, value is foreign_value*rate
+	rate          decimal.Decimal
+}
+
+// entry_header is the entry as it is posted, its lines are the rows of the journal that have its entry_number.
+// the lines are saved as they are entered so one sale with cash, revenue, tax and discount is one entry
+type entry_header struct {
+	entry_number      int
+	date              time.Time
+	description, name string
+	employee_name     string
+	entry_date        time.Time
 }
 
 type financial_analysis struct {
 	current_assets, current_liabilities, cash, short_term_investments, net_receivables, net_credit_sales,
 	average_net_receivables, cost_of_goods_sold, average_inventory, net_income, net_sales, average_assets, average_equity,
 	preferred_dividends, average_common_stockholders_equity, market_price_per_shares_outstanding, cash_dividends,
-	total_debt, total_assets, ebitda, interest_expense, weighted_average_common_shares_outstanding float64
+	total_debt, total_assets, ebitda, interest_expense, weighted_average_common_shares_outstanding decimal.Decimal
 }
 
 type financial_analysis_statement struct {
-	current_ratio                        float64 // current_assets / current_liabilities
-	acid_test                            float64 // (cash + short_term_investments + net_receivables) / current_liabilities
-	receivables_turnover                 float64 // net_credit_sales / average_net_receivables
-	inventory_turnover                   float64 // cost_of_goods_sold / average_inventory
-	asset_turnover                       float64 // net_sales / average_assets
-	profit_margin                        float64 // net_income / net_sales
-	return_on_assets                     float64 // net_income / average_assets
-	return_on_equity                     float64 // net_income / average_equity
-	payout_ratio                         float64 // cash_dividends / net_income
-	debt_to_total_assets_ratio           float64 // total_debt / total_assets
-	times_interest_earned                float64 // ebitda / interest_expense
-	return_on_common_stockholders_equity float64 // (net_income - preferred_dividends) / average_common_stockholders_equity
-	earnings_per_share                   float64 // (net_income - preferred_dividends) / weighted_average_common_shares_outstanding
-	price_earnings_ratio                 float64 // market_price_per_shares_outstanding / earnings_per_share
+	current_ratio                        decimal.Decimal // current_assets / current_liabilities
+	acid_test                            decimal.Decimal // (cash + short_term_investments + net_receivables) / current_liabilities
+	receivables_turnover                 decimal.Decimal // net_credit_sales / average_net_receivables
+	inventory_turnover                   decimal.Decimal // cost_of_goods_sold / average_inventory
+	asset_turnover                       decimal.Decimal // net_sales / average_assets
+	profit_margin                        decimal.Decimal // net_income / net_sales
+	return_on_assets                     decimal.Decimal // net_income / average_assets
+	return_on_equity                     decimal.Decimal // net_income / average_equity
+	payout_ratio                         decimal.Decimal // cash_dividends / net_income
+	debt_to_total_assets_ratio           decimal.Decimal // total_debt / total_assets
+	times_interest_earned                decimal.Decimal // ebitda / interest_expense
+	return_on_common_stockholders_equity decimal.Decimal // (net_income - preferred_dividends) / average_common_stockholders_equity
+	earnings_per_share                   decimal.Decimal // (net_income - preferred_dividends) / weighted_average_common_shares_outstanding
+	price_earnings_ratio                 decimal.Decimal // market_price_per_shares_outstanding / earnings_per_share
 }
 
 type filtered_statement struct {
 	key_account_flow, key_account, key_name, key_vpq, key_number string
-	number                                                       float64
+	number                                                       decimal.Decimal
 }
 
 type one_step_distribution struct {
@@ -147,73 +186,30 @@ type Managerial_Accounting struct {
 }
 
 var (
-	db                   *sql.DB
-	inventory            []string
-	standard_days        = [7]string{"Saturday", "Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday"}
-	adjusting_methods    = [4]string{"linear", "exponential", "logarithmic", "expire"}
-	depreciation_methods = [3]string{"linear", "exponential", "logarithmic"}
-	Now                  = time.Now()
+	standard_days            = [7]string{"Saturday", "Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday"}
+	adjusting_methods        = [4]string{"linear", "exponential", "logarithmic", "expire"}
+	depreciation_methods     = [3]string{"linear", "exponential", "logarithmic"}
+	cash_flow_categories     = [3]string{"operating", "investing", "financing"}
+	recurring_frequencies    = [4]string{"daily", "weekly", "monthly", "yearly"}
+	template_parameter_kinds = [5]string{"value", "quantity", "percent", "account", "barcode"}
+	template_line_methods    = [4]string{"fixed", "percent_of_value", "percent_of_line", "formula"}
+	Now                      = time.Now()
 )
 
-func (s Financial_accounting) initialize() {
-	db, _ = sql.Open(s.DriverName, s.DataSourceName)
-	err := db.Ping()
+func (s *Financial_accounting) initialize() {
+	var err error
+	s.db, err = open_storage(s.DriverName, s.DataSourceName, s.Database_name)
 	error_fatal(err)
-	db.Exec("create database if not exists " + s.Database_name)
-	_, err = db.Exec("USE " + s.Database_name)
-	error_fatal(err)
-	db.Exec("create table if not exists journal (date text,entry_number integer,account text,value real,price real,quantity real,barcode text,entry_expair text,description text,name text,employee_name text,entry_date text,reverse bool)")
-	db.Exec("create table if not exists inventory (date text,account text,price real,quantity real,barcode text,entry_expair text,name text,employee_name text,entry_date text)")
+	error_fatal(s.db.migrate(s.date_layout))
 
-	var all_accounts []string
-	for _, i := range s.accounts {
-		if !s.is_father("", i.name) {
-			log.Panic(i.name, " account does not ends in ''")
-		}
-		all_accounts = append(all_accounts, i.name)
-		switch {
-		case IS_IN(i.cost_flow_type, []string{"fifo", "lifo", "wma"}) && !s.is_father(s.retained_earnings, i.name) && !i.is_credit:
-			inventory = append(inventory, i.name)
-		case i.cost_flow_type == "":
-		default:
-			log.Panic(i.cost_flow_type, " for ", i.name, " is not in [fifo,lifo,wma,''] or you can't use it with ", s.retained_earnings, " or is_credit==true")
-		}
+	if s.value_places == 0 && s.quantity_places == 0 {
+		s.value_places, s.quantity_places = default_value_places, default_quantity_places
 	}
-
-	switch {
-	case !s.is_father(s.assets, s.current_assets):
-		log.Panic(s.assets, " should be one of the fathers of ", s.current_assets)
-	case !s.is_father(s.current_assets, s.cash_and_cash_equivalents):
-		log.Panic(s.current_assets, " should be one of the fathers of ", s.cash_and_cash_equivalents)
-	case !s.is_father(s.current_assets, s.short_term_investments):
-		log.Panic(s.current_assets, " should be one of the fathers of ", s.short_term_investments)
-	case !s.is_father(s.current_assets, s.receivables):
-		log.Panic(s.current_assets, " should be one of the fathers of ", s.receivables)
-	case !s.is_father(s.current_assets, s.inventory):
-		log.Panic(s.current_assets, " should be one of the fathers of ", s.inventory)
-	case !s.is_father(s.liabilities, s.current_liabilities):
-		log.Panic(s.liabilities, " should be one of the fathers of ", s.current_liabilities)
-	case !s.is_father(s.equity, s.retained_earnings):
-		log.Panic(s.equity, " should be one of the fathers of ", s.retained_earnings)
-	case !s.is_father(s.retained_earnings, s.dividends):
-		log.Panic(s.retained_earnings, " should be one of the fathers of ", s.dividends)
-	case !s.is_father(s.retained_earnings, s.income_statement):
-		log.Panic(s.retained_earnings, " should be one of the fathers of ", s.income_statement)
-	case !s.is_father(s.income_statement, s.ebitda):
-		log.Panic(s.income_statement, " should be one of the fathers of ", s.ebitda)
-	case !s.is_father(s.income_statement, s.interest_expense):
-		log.Panic(s.income_statement, " should be one of the fathers of ", s.interest_expense)
-	case !s.is_father(s.ebitda, s.sales):
-		log.Panic(s.ebitda, " should be one of the fathers of ", s.sales)
-	case !s.is_father(s.ebitda, s.cost_of_goods_sold):
-		log.Panic(s.ebitda, " should be one of the fathers of ", s.cost_of_goods_sold)
-	case !s.is_father(s.ebitda, s.discounts):
-		log.Panic(s.ebitda, " should be one of the fathers of ", s.discounts)
-	case !s.is_father(s.discounts, s.invoice_discount):
-		log.Panic(s.discounts, " should be one of the fathers of ", s.invoice_discount)
-	}
-	check_if_duplicates(all_accounts)
-	check_accounts("account", "inventory", " is not have fifo lifo wma on cost_flow_type field", inventory)
+	error_fatal(s.load_accounts())
+	error_fatal(s.use_accounts(s.accounts))
+	error_fatal(s.check_accounts(s.db, " is not have fifo lifo wma on cost_flow_type field", s.inventory_accounts))
+	// the config is checked with the chart of the database because the accounts can be renamed after the first initialize
+	error_fatal(s.check_config())
 
 	// entry_number := entry_number()
 	// var array_to_insert []journal_tag
@@ -230,72 +226,153 @@ func (s Financial_accounting) initialize() {
 	// array_to_insert = append(array_to_insert, expair_expenses)
 	// s.insert_to_database(array_to_insert, true, false, false)
 	// db.Exec("delete from inventory where entry_expair<? and entry_expair!='0001-01-01 00:00:00 +0000 UTC'", Now.String())
-	db.Exec("delete from inventory where quantity=0")
+	error_fatal(s.db.delete_empty_inventory())
 
-	var journal [][]Account_value_quantity_barcode
-	var double_entry []Account_value_quantity_barcode
-	previous_entry_number := 1
-	rows, _ := db.Query("select entry_number,account,value from journal order by date,entry_number")
-	for rows.Next() {
-		var entry_number int
-		var tag Account_value_quantity_barcode
-		rows.Scan(&entry_number, &tag.Account, &tag.value)
-		if previous_entry_number != entry_number {
-			s.check_debit_equal_credit(double_entry, true)
-			journal = append(journal, double_entry)
-			double_entry = []Account_value_quantity_barcode{}
+	// every entry is checked like journal_entry checks it so the compound entries are allowed
+	all_journal, err := s.db.journal()
+	error_fatal(err)
+	for _, one_entry := range group_by_entry_number(all_journal) {
+		var entry []Account_value_quantity_barcode
+		for _, row := range one_entry {
+			entry = append(entry, Account_value_quantity_barcode{Account: row.account, value: row.value})
 		}
-		double_entry = append(double_entry, tag)
-		previous_entry_number = entry_number
+		_, _, err = s.check_debit_equal_credit(entry, false)
+		error_fatal(err)
 	}
 }
 
+// journal_entry is the entry point of the employees, the entries that match an approval rule are refused here and
+// should be saved by save_draft. the entries that the program posts by itself use post_entry
 func (s Financial_accounting) journal_entry(array_of_entry []Account_value_quantity_barcode, insert, auto_completion bool, date time.Time, entry_expair time.Time, adjusting_method string,
-	description string, name string, employee_name string, array_day_start_end []day_start_end) []journal_tag {
-	array_day_start_end = check_the_params(entry_expair, adjusting_method, date, array_of_entry, array_day_start_end)
-	array_of_entry = group_by_account_and_barcode(array_of_entry)
-	array_of_entry = remove_zero_values(array_of_entry)
-	find_barcode(array_of_entry)
-	array_of_entry = s.auto_completion_the_entry(array_of_entry, auto_completion)
-	array_of_entry = s.auto_completion_the_invoice_discount(auto_completion, array_of_entry)
-	array_of_entry = group_by_account_and_barcode(array_of_entry)
-	array_of_entry = remove_zero_values(array_of_entry)
-	s.can_the_account_be_negative(array_of_entry)
-	debit_entries, credit_entries := s.check_debit_equal_credit(array_of_entry, false)
-	simple_entries := s.convert_to_simple_entry(debit_entries, credit_entries)
+	description string, name string, employee_name string, array_day_start_end []day_start_end) ([]journal_tag, error) {
+	return s.post_entry(array_of_entry, insert, auto_completion, date, entry_expair, adjusting_method, description, name, employee_name, array_day_start_end, true)
+}
+
+// post_entry checks and posts the entry, the approval rules are checked only if check_approval is true so the approved drafts
+// and the reclassifications are posted without them
+func (s Financial_accounting) post_entry(array_of_entry []Account_value_quantity_barcode, insert, auto_completion bool, date time.Time, entry_expair time.Time, adjusting_method string,
+	description string, name string, employee_name string, array_day_start_end []day_start_end, check_approval bool) ([]journal_tag, error) {
 	var all_array_to_insert []journal_tag
-	for _, simple_entry := range simple_entries {
-		array_to_insert := insert_to_journal_tag(simple_entry, date, entry_expair, description, name, employee_name)
+	err := s.db.transaction(func(tx storage) error {
+		err := tx.lock_ledger()
+		if err != nil {
+			return err
+		}
+		array_day_start_end, err = s.check_the_params(entry_expair, adjusting_method, date, array_of_entry, array_day_start_end)
+		if err != nil {
+			return err
+		}
+		s.account_of_code(array_of_entry)
+		s.round_entry(array_of_entry)
+		array_of_entry = group_by_account_and_barcode(array_of_entry)
+		array_of_entry = remove_zero_values(array_of_entry)
+		err = find_barcode(tx, array_of_entry)
+		if err != nil {
+			return err
+		}
+		err = s.check_known_accounts(array_of_entry)
+		if err != nil {
+			return err
+		}
+		array_of_entry, err = s.auto_completion_the_entry(tx, array_of_entry, auto_completion)
+		if err != nil {
+			return err
+		}
+		err = s.check_known_accounts(array_of_entry)
+		if err != nil {
+			return err
+		}
+		err = s.check_company_currency(array_of_entry)
+		if err != nil {
+			return err
+		}
+		array_of_entry, err = s.auto_completion_the_invoice_discount(auto_completion, array_of_entry)
+		if err != nil {
+			return err
+		}
+		s.round_entry(array_of_entry)
+		array_of_entry = group_by_account_and_barcode(array_of_entry)
+		array_of_entry = remove_zero_values(array_of_entry)
+		err = s.can_the_account_be_negative(tx, array_of_entry)
+		if err != nil {
+			return err
+		}
+		_, _, err = s.check_debit_equal_credit(array_of_entry, false)
+		if err != nil {
+			return err
+		}
+		if insert && check_approval {
+			err = s.check_approval_rules(array_of_entry)
+			if err != nil {
+				return err
+			}
+		}
+		all_array_to_insert, err = insert_to_journal_tag(array_of_entry, date, entry_expair, description, name, employee_name)
+		if err != nil {
+			return err
+		}
 		if IS_IN(adjusting_method, depreciation_methods[:]) {
-			adjusted_array_to_insert := adjuste_the_array(entry_expair, date, array_day_start_end, array_to_insert, adjusting_method, description, name, employee_name)
+			adjusted_array_to_insert, err := s.adjuste_the_array(entry_expair, date, array_day_start_end, all_array_to_insert, adjusting_method, description, name, employee_name)
+			if err != nil {
+				return err
+			}
 			adjusted_array_to_insert = transpose(adjusted_array_to_insert)
-			array_to_insert = unpack_the_array(array_to_insert, adjusted_array_to_insert)
+			err = s.balance_the_periods(adjusted_array_to_insert)
+			if err != nil {
+				return err
+			}
+			all_array_to_insert = unpack_the_array(all_array_to_insert, adjusted_array_to_insert)
+		}
+		err = s.check_periods(tx, all_array_to_insert, employee_name)
+		if err != nil {
+			return err
 		}
-		all_array_to_insert = append(all_array_to_insert, array_to_insert...)
+		return s.insert_to_database(tx, all_array_to_insert, insert, insert, insert)
+	})
+	if err != nil {
+		return nil, err
 	}
-	s.insert_to_database(all_array_to_insert, insert, insert, insert)
-	return all_array_to_insert
+	return all_array_to_insert, nil
 }
 
-func (s Financial_accounting) financial_statements(start_date, end_date time.Time, periods int, names []string, in_names bool) ([]map[string]map[string]map[string]map[string]map[string]float64, []financial_analysis_statement, []journal_tag) {
-	check_dates(start_date, end_date)
+func (s Financial_accounting) financial_statements(start_date, end_date time.Time, periods int, names []string, in_names bool) ([]map[string]map[string]map[string]map[string]map[string]decimal.Decimal, []financial_analysis_statement, []journal_tag, error) {
+	err := check_dates(start_date, end_date)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 	days := int(end_date.Sub(start_date).Hours() / 24)
-	var journal []journal_tag
-	rows, _ := db.Query("select * from journal order by date,entry_number")
-	for rows.Next() {
-		var entry journal_tag
-		rows.Scan(&entry.date, &entry.entry_number, &entry.account, &entry.value, &entry.price, &entry.quantity, &entry.barcode, &entry.entry_expair, &entry.description, &entry.name, &entry.employee_name, &entry.entry_date, &entry.reverse)
-		journal = append(journal, entry)
-	}
-	statements := []map[string]map[string]map[string]map[string]map[string]float64{}
+	snapshot, journal, err := s.statement_snapshot(start_date.AddDate(0, 0, -days*(periods-1)), end_date)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for _, row := range snapshot {
+		if !s.is_account(row.account) {
+			return nil, nil, nil, ErrUnknownAccount{row.account}
+		}
+	}
+	for _, entry := range journal {
+		if !s.is_account(entry.account) {
+			return nil, nil, nil, ErrUnknownAccount{entry.account}
+		}
+	}
+	statements := []map[string]map[string]map[string]map[string]map[string]decimal.Decimal{}
 	for a := 0; a < periods; a++ {
-		flow_statement, nan_flow_statement := s.statement(journal, start_date.AddDate(0, 0, -days*a), end_date.AddDate(0, 0, -days*a))
+		flow_statement, nan_flow_statement, err := s.statement(snapshot, journal, start_date.AddDate(0, 0, -days*a), end_date.AddDate(0, 0, -days*a))
+		if err != nil {
+			return nil, nil, nil, err
+		}
 		statement := combine_statements(flow_statement, nan_flow_statement)
-		statement = s.sum_1st_column(statement)
-		statement = s.sum_2nd_column(statement)
+		statement, err = s.sum_1st_column(statement)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		statement, err = s.sum_2nd_column(statement)
+		if err != nil {
+			return nil, nil, nil, err
+		}
 		sum_3rd_column(statement, []string{}, []string{}, "all", false)
 		sum_3rd_column(statement, names, []string{"all"}, "names", in_names)
-		vertical_analysis(statement, float64(days))
+		vertical_analysis(statement, decimal.NewFromInt(int64(days)))
 		statements = append(statements, statement)
 	}
 	var all_analysis []financial_analysis_statement
@@ -306,10 +383,10 @@ func (s Financial_accounting) financial_statements(start_date, end_date time.Tim
 		analysis := s.analysis(statement_current)
 		all_analysis = append(all_analysis, analysis)
 	}
-	return statements, all_analysis, journal
+	return statements, all_analysis, journal, nil
 }
 
-func (s Financial_accounting) statement_filter(all_financial_statements []map[string]map[string]map[string]map[string]map[string]float64, account_flow_slice, account_slice, name_slice, vpq_slice, number_slice []string,
+func (s Financial_accounting) statement_filter(all_financial_statements []map[string]map[string]map[string]map[string]map[string]decimal.Decimal, account_flow_slice, account_slice, name_slice, vpq_slice, number_slice []string,
 	in_account_flow_slice, in_account_slice, in_name_slice, in_vpq_slice, in_number_slice bool) [][]filtered_statement {
 	var all_statements_struct [][]filtered_statement
 	for _, statement := range all_financial_statements {
@@ -335,97 +412,162 @@ func (s Financial_accounting) statement_filter(all_financial_statements []map[st
 				}
 			}
 		}
-		var indexa int
-		for _, a := range s.accounts {
-			for indexb, b := range statement_struct {
-				if a.name == b.key_account {
-					statement_struct[indexa], statement_struct[indexb] = statement_struct[indexb], statement_struct[indexa]
-					indexa++
-					break
-				}
-			}
-		}
+		s.sort_by_code(statement_struct)
 		all_statements_struct = append(all_statements_struct, statement_struct)
 	}
 	return all_statements_struct
 }
 
-func (s Financial_accounting) can_the_account_be_negative(array_of_entry []Account_value_quantity_barcode) {
+// can_the_account_be_negative checks the balance of every line, and the lines of the contra accounts can't make the balance
+// of their fathers negative too like the accumulated depreciation that is more than the cost of the equipment
+func (s Financial_accounting) can_the_account_be_negative(tx storage, array_of_entry []Account_value_quantity_barcode) error {
 	for _, entry := range array_of_entry {
-		if !(s.is_father(s.equity, entry.Account) && s.is_credit(entry.Account)) {
-			var account_balance float64
-			db.QueryRow("select sum(value) from journal where account=? and date<?", entry.Account, Now.String()).Scan(&account_balance)
-			if account_balance+entry.value < 0 {
-				log.Panic("you cant enter ", entry, " because you have ", account_balance, " and that will make the balance of ", entry.Account, " negative ", account_balance+entry.value, " and that you just can do it in equity_normal accounts not other accounts")
+		if !s.can_be_negative(entry.Account) {
+			account_balance, err := tx.account_balance(entry.Account, Now)
+			if err != nil {
+				return err
+			}
+			if account_balance.Add(entry.value).IsNegative() {
+				return ErrNegativeBalance{entry.Account, account_balance, entry.value}
 			}
 		}
+		if !s.is_contra(entry.Account) {
+			continue
+		}
+		father := s.accounts[index_of_account(s.accounts, entry.Account)].father
+		if s.can_be_negative(father) {
+			continue
+		}
+		balance, err := s.net_balance(tx, father)
+		if err != nil {
+			return err
+		}
+		var lines []journal_tag
+		for _, line := range array_of_entry {
+			lines = append(lines, journal_tag{account: line.Account, value: line.value})
+		}
+		change, err := s.net_change(father, lines)
+		if err != nil {
+			return err
+		}
+		if balance.Add(change).IsNegative() {
+			return ErrNegativeBalance{father, balance, change}
+		}
 	}
+	return nil
 }
 
-func (s Financial_accounting) auto_completion_the_invoice_discount(auto_completion bool, array_of_entry []Account_value_quantity_barcode) []Account_value_quantity_barcode {
+// net_balance is the balance of the account with its sons on the side of the account, so its contra sons are subtracted
+func (s Financial_accounting) net_balance(tx storage, name string) (decimal.Decimal, error) {
+	var lines []journal_tag
+	for _, a := range s.accounts {
+		if !s.is_father(name, a.name) {
+			continue
+		}
+		balance, err := tx.account_balance(a.name, Now)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		lines = append(lines, journal_tag{account: a.name, value: balance})
+	}
+	return s.net_change(name, lines)
+}
+
+// net_change sums the lines of the account and its sons on the side of the account
+func (s Financial_accounting) net_change(name string, lines []journal_tag) (decimal.Decimal, error) {
+	var change decimal.Decimal
+	for _, line := range lines {
+		if !s.is_father(name, line.account) {
+			continue
+		}
+		same_side, err := s.same_side(name, line.account)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		if same_side {
+			change = change.Add(line.value)
+		} else {
+			change = change.Sub(line.value)
+		}
+	}
+	return change, nil
+}
+
+func (s Financial_accounting) auto_completion_the_invoice_discount(auto_completion bool, array_of_entry []Account_value_quantity_barcode) ([]Account_value_quantity_barcode, error) {
 	if auto_completion {
-		var total_invoice_before_invoice_discount, discount float64
+		var total_invoice_before_invoice_discount, discount decimal.Decimal
 		for _, entry := range array_of_entry {
-			if s.is_father(s.income_statement, entry.Account) && s.is_credit(entry.Account) {
-				total_invoice_before_invoice_discount += entry.value
-			} else if s.is_father(s.discounts, entry.Account) && !s.is_credit(entry.Account) {
-				total_invoice_before_invoice_discount -= entry.value
+			is_credit, err := s.is_credit(entry.Account)
+			if err != nil {
+				return nil, err
+			}
+			if s.is_father(s.income_statement, entry.Account) && is_credit {
+				total_invoice_before_invoice_discount = total_invoice_before_invoice_discount.Add(entry.value)
+			} else if s.is_father(s.discounts, entry.Account) && !is_credit {
+				total_invoice_before_invoice_discount = total_invoice_before_invoice_discount.Sub(entry.value)
 			}
 		}
 		for _, i := range s.Invoice_discounts_list {
-			if total_invoice_before_invoice_discount >= i[0] {
+			if total_invoice_before_invoice_discount.GreaterThanOrEqual(i[0]) {
 				discount = i[1]
 			}
 		}
 		invoice_discount := discount_tax_calculator(total_invoice_before_invoice_discount, discount)
-		array_of_entry = append(array_of_entry, Account_value_quantity_barcode{s.invoice_discount, invoice_discount, 1, ""})
+		array_of_entry = append(array_of_entry, Account_value_quantity_barcode{s.invoice_discount, invoice_discount, decimal.NewFromInt(1), ""})
 	}
-	return array_of_entry
+	return array_of_entry, nil
 }
 
-func (s Financial_accounting) auto_completion_the_entry(array_of_entry []Account_value_quantity_barcode, auto_completion bool) []Account_value_quantity_barcode {
+func (s Financial_accounting) auto_completion_the_entry(tx storage, array_of_entry []Account_value_quantity_barcode, auto_completion bool) ([]Account_value_quantity_barcode, error) {
 	for index, entry := range array_of_entry {
-		costs := s.cost_flow(entry.Account, entry.quantity, entry.barcode, false)
-		if costs != 0 {
-			array_of_entry[index] = Account_value_quantity_barcode{entry.Account, -costs, entry.quantity, entry.barcode}
+		costs, err := s.cost_flow(tx, entry.Account, entry.quantity, entry.barcode, false)
+		if err != nil {
+			return nil, err
+		}
+		if !costs.IsZero() {
+			array_of_entry[index] = Account_value_quantity_barcode{entry.Account, costs.Neg(), entry.quantity, entry.barcode}
 		}
 		if auto_completion {
 			for _, complement := range s.auto_complete_entries {
-				if complement[0].account == entry.Account && (entry.quantity >= 0) == (complement[0].value_or_percent >= 0) {
-					if costs == 0 {
-						array_of_entry[index] = Account_value_quantity_barcode{complement[0].account, complement[0].price * entry.quantity, entry.quantity, ""}
+				if complement[0].account == entry.Account && !entry.quantity.IsNegative() == !complement[0].value_or_percent.IsNegative() {
+					if costs.IsZero() {
+						array_of_entry[index] = Account_value_quantity_barcode{complement[0].account, complement[0].price.Mul(entry.quantity), entry.quantity, ""}
 					}
 					for _, i := range complement[1:] {
 						switch i.method {
 						case "copy_abs":
-							array_of_entry = append(array_of_entry, Account_value_quantity_barcode{i.account, math.Abs(array_of_entry[index].value), math.Abs(array_of_entry[index].quantity), ""})
+							array_of_entry = append(array_of_entry, Account_value_quantity_barcode{i.account, array_of_entry[index].value.Abs(), array_of_entry[index].quantity.Abs(), ""})
 						case "copy":
 							array_of_entry = append(array_of_entry, Account_value_quantity_barcode{i.account, array_of_entry[index].value, array_of_entry[index].quantity, ""})
 						case "quantity_ratio":
-							array_of_entry = append(array_of_entry, Account_value_quantity_barcode{i.account, math.Abs(array_of_entry[index].quantity) * i.price * i.value_or_percent, math.Abs(array_of_entry[index].quantity) * i.value_or_percent, ""})
+							array_of_entry = append(array_of_entry, Account_value_quantity_barcode{i.account, array_of_entry[index].quantity.Abs().Mul(i.price).Mul(i.value_or_percent), array_of_entry[index].quantity.Abs().Mul(i.value_or_percent), ""})
 						case "value":
-							array_of_entry = append(array_of_entry, Account_value_quantity_barcode{i.account, i.value_or_percent, i.value_or_percent / i.price, ""})
+							array_of_entry = append(array_of_entry, Account_value_quantity_barcode{i.account, i.value_or_percent, divide(i.value_or_percent, i.price), ""})
 						default:
-							log.Panic(i.method, "in the method field for ", i, " dose not exist you just can use copy_abs or copy or quantity_ratio or value")
+							return nil, ErrInvalidConfig{fmt.Sprint(i.method, " in the method field for ", i, " dose not exist you just can use copy_abs or copy or quantity_ratio or value")}
 						}
 					}
 				}
 			}
 		}
 	}
-	return array_of_entry
+	return array_of_entry, nil
 }
 
-func (s Financial_accounting) invoice(array_of_journal_tag []journal_tag) []invoice_struct {
+func (s Financial_accounting) invoice(array_of_journal_tag []journal_tag) ([]invoice_struct, error) {
 	m := map[string]*invoice_struct{}
 	for _, entry := range array_of_journal_tag {
+		is_credit, err := s.is_credit(entry.account)
+		if err != nil {
+			return nil, err
+		}
 		var key string
 		switch {
-		case s.is_father(s.assets, entry.account) && !s.is_credit(entry.account) && !IS_IN(entry.account, inventory) && entry.value > 0:
+		case s.is_father(s.assets, entry.account) && !is_credit && !IS_IN(entry.account, s.inventory_accounts) && entry.value.IsPositive():
 			key = "total"
-		case s.is_father(s.discounts, entry.account) && !s.is_credit(entry.account):
+		case s.is_father(s.discounts, entry.account) && !is_credit:
 			key = "total discounts"
-		case s.is_father(s.sales, entry.account) && s.is_credit(entry.account):
+		case s.is_father(s.sales, entry.account) && is_credit:
 			key = entry.account
 		default:
 			continue
@@ -435,191 +577,328 @@ func (s Financial_accounting) invoice(array_of_journal_tag []journal_tag) []invo
 			sums = &invoice_struct{}
 			m[key] = sums
 		}
-		sums.value += entry.value
-		sums.quantity += entry.quantity
+		sums.value = sums.value.Add(entry.value)
+		sums.quantity = sums.quantity.Add(entry.quantity)
 	}
 	invoice := []invoice_struct{}
 	for k, v := range m {
-		invoice = append(invoice, invoice_struct{k, v.value, v.value / v.quantity, v.quantity})
+		invoice = append(invoice, invoice_struct{k, v.value, divide(v.value, v.quantity), v.quantity})
 	}
-	return invoice
+	return invoice, nil
 }
 
-func (s Financial_accounting) reverse_entry(entry_number uint, employee_name string) {
-	var array_of_entry_to_reverse, array_of_journal_tag []journal_tag
-	rows, _ := db.Query("select * from journal where entry_number=? order by date", entry_number)
-	for rows.Next() {
-		var tag journal_tag
-		rows.Scan(&tag.date, &tag.entry_number, &tag.account, &tag.value, &tag.price, &tag.quantity, &tag.barcode, &tag.entry_expair, &tag.description, &tag.name, &tag.employee_name, &tag.entry_date, &tag.reverse)
-		array_of_journal_tag = append(array_of_journal_tag, tag)
-	}
-	if len(array_of_journal_tag) == 0 {
-		log.Panic("this entry not exist")
-	}
-	for _, entry := range array_of_journal_tag {
-		if !entry.reverse {
-			if s.parse_date(entry.date).Before(Now) {
-				db.Exec("update journal set reverse=True where date=? and entry_number=? and account=? and value=? and price=? and quantity=? and barcode=? and entry_expair=? and description=? and name=? and employee_name=? and entry_date=? and reverse=?",
-					entry.date, entry.entry_number, entry.account, entry.value, entry.price, entry.quantity, entry.barcode, entry.entry_expair, entry.description, entry.name, entry.employee_name, entry.entry_date, entry.reverse)
-				entry.description = "(reverse entry for entry number " + strconv.Itoa(entry.entry_number) + " entered by " + entry.employee_name + " and revised by " + employee_name + ")"
-				entry.date = Now.String()
-				entry.value *= -1
-				entry.quantity *= -1
-				entry.entry_expair = time.Time{}.String()
-				entry.employee_name = employee_name
-				entry.entry_date = Now.String()
-				array_of_entry_to_reverse = append(array_of_entry_to_reverse, entry)
-				weighted_average([]string{entry.account})
-			} else {
-				db.Exec("delete from journal where date=? and entry_number=? and account=? and value=? and price=? and quantity=? and barcode=? and entry_expair=? and description=? and name=? and employee_name=? and entry_date=? and reverse=?",
-					entry.date, entry.entry_number, entry.account, entry.value, entry.price, entry.quantity, entry.barcode, entry.entry_expair, entry.description, entry.name, entry.employee_name, entry.entry_date, entry.reverse)
+// posted_entry returns the header of the entry and its lines as they are posted, the lines of the adjusted entries are ordered by date
+func (s Financial_accounting) posted_entry(entry_number uint) (entry_header, []journal_tag, error) {
+	var header entry_header
+	var lines []journal_tag
+	err := s.db.transaction(func(tx storage) error {
+		var err error
+		header, err = tx.header_of_entry_number(entry_number)
+		if err == sql.ErrNoRows {
+			return ErrEntryNotFound{entry_number}
+		}
+		if err != nil {
+			return err
+		}
+		lines, err = tx.journal_of_entry_number(entry_number)
+		return err
+	})
+	return header, lines, err
+}
+
+func (s Financial_accounting) reverse_entry(entry_number uint, employee_name string) error {
+	return s.db.transaction(func(tx storage) error {
+		err := tx.lock_ledger()
+		if err != nil {
+			return err
+		}
+		// every period of the adjusted entries is reversed in its own entry so every reversing entry balances like its period
+		var periods_to_reverse [][]journal_tag
+		var period_date time.Time
+		array_of_journal_tag, err := tx.journal_of_entry_number(entry_number)
+		if err != nil {
+			return err
+		}
+		if len(array_of_journal_tag) == 0 {
+			return ErrEntryNotFound{entry_number}
+		}
+		// the reverse lines are dated now
+		err = s.check_periods(tx, append(array_of_journal_tag, journal_tag{date: Now}), employee_name)
+		if err != nil {
+			return err
+		}
+		for _, entry := range array_of_journal_tag {
+			if !entry.reverse {
+				if entry.date.Before(Now) {
+					err = tx.set_reverse(entry.id)
+					if err != nil {
+						return err
+					}
+					if len(periods_to_reverse) == 0 || !entry.date.Equal(period_date) {
+						periods_to_reverse = append(periods_to_reverse, nil)
+						period_date = entry.date
+					}
+					entry.description = "(reverse entry for entry number " + strconv.Itoa(entry.entry_number) + " entered by " + entry.employee_name + " and revised by " + employee_name + ")"
+					entry.date = Now
+					entry.value = entry.value.Neg()
+					entry.quantity = entry.quantity.Neg()
+					entry.foreign_value = entry.foreign_value.Neg()
+					entry.entry_expair = time.Time{}
+					entry.employee_name = employee_name
+					entry.entry_date = Now
+					periods_to_reverse[len(periods_to_reverse)-1] = append(periods_to_reverse[len(periods_to_reverse)-1], entry)
+					err = weighted_average(tx, []string{entry.account})
+				} else {
+					err = tx.delete_journal(entry.id)
+					if err == nil {
+						err = s.undo_inventory(tx, entry)
+					}
+					if err == nil {
+						// the snapshots after it are made again when they are needed
+						err = tx.delete_snapshots(entry.date)
+					}
+				}
+				if err != nil {
+					return err
+				}
 			}
 		}
+		for _, period := range periods_to_reverse {
+			err = s.insert_to_database(tx, period, true, true, true)
+			if err != nil {
+				return err
+			}
+		}
+		// the header of the entry that is deleted by all its lines goes with them
+		remaining, err := tx.journal_of_entry_number(entry_number)
+		if err != nil || len(remaining) != 0 {
+			return err
+		}
+		return tx.delete_entry_header(int(entry_number))
+	})
+}
+
+// undo_inventory takes back what the deleted line did to the inventory, the quantity it bought is taken from its layer
+// and the quantity it sold is put back as a layer at its cost
+func (s Financial_accounting) undo_inventory(tx storage, entry journal_tag) error {
+	if !IS_IN(entry.account, s.inventory_accounts) || entry.quantity.IsZero() {
+		return nil
 	}
-	s.insert_to_database(array_of_entry_to_reverse, true, true, true)
+	if entry.quantity.IsNegative() {
+		entry.value, entry.quantity = entry.value.Neg(), entry.quantity.Neg()
+		err := tx.insert_inventory(entry)
+		if err != nil {
+			return err
+		}
+	} else {
+		layer, err := tx.inventory_layer_of_line(entry)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		// the layer that is sold before it can't be taken back, the sale should be reversed first
+		if err == sql.ErrNoRows || layer.quantity.LessThan(entry.quantity) {
+			return ErrInsufficientInventory{entry.account, entry.barcode, entry.quantity, layer.quantity}
+		}
+		if layer.quantity.Equal(entry.quantity) {
+			err = tx.delete_inventory_layer(layer.id)
+		} else {
+			err = tx.set_inventory_layer_quantity(layer.id, layer.quantity.Sub(entry.quantity))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if s.return_cost_flow_type(entry.account) == "wma" {
+		return weighted_average(tx, []string{entry.account})
+	}
+	return nil
 }
 
-func (s Financial_accounting) insert_to_database(array_of_journal_tag []journal_tag, insert_into_journal, insert_into_inventory, inventory_flow bool) {
-	entry_number := float64(entry_number())
+func (s Financial_accounting) insert_to_database(tx storage, array_of_journal_tag []journal_tag, insert_into_journal, insert_into_inventory, inventory_flow bool) error {
+	if len(array_of_journal_tag) == 0 {
+		return nil
+	}
+	// all the lines of the entry take one number, the lines of the adjusted entries are told apart by their dates.
+	// the previews take 0 so they leave no gaps in the numbers
+	var entry_number int
+	var err error
+	if insert_into_journal {
+		entry_number, err = tx.next_entry_numbers(1)
+		if err != nil {
+			return err
+		}
+	}
 	for indexa, entry := range array_of_journal_tag {
-		entry.entry_number = int(entry_number)
-		array_of_journal_tag[indexa].entry_number = int(entry_number)
-		entry_number += 0.5
+		entry.entry_number = entry_number
+		array_of_journal_tag[indexa].entry_number = entry_number
 		if insert_into_journal {
-			db.Exec("insert into journal(date,entry_number,account,value,price,quantity,barcode,entry_expair,description,name,employee_name,entry_date,reverse) values (?,?,?,?,?,?,?,?,?,?,?,?,?)",
-				&entry.date, &entry.entry_number, &entry.account, &entry.value, &entry.price, &entry.quantity, &entry.barcode,
-				&entry.entry_expair, &entry.description, &entry.name, &entry.employee_name, &entry.entry_date, &entry.reverse)
+			err = tx.insert_journal(entry)
+			if err != nil {
+				return err
+			}
 		}
-		if IS_IN(entry.account, inventory) {
-			costs := s.cost_flow(entry.account, entry.quantity, entry.barcode, inventory_flow)
-			if insert_into_inventory && costs == 0 {
-				db.Exec("insert into inventory(date,account,price,quantity,barcode,entry_expair,name,employee_name,entry_date)values (?,?,?,?,?,?,?,?,?)",
-					&entry.date, &entry.account, &entry.price, &entry.quantity, &entry.barcode, &entry.entry_expair, &entry.name, &entry.employee_name, &entry.entry_date)
-
+		if IS_IN(entry.account, s.inventory_accounts) {
+			costs, err := s.cost_flow(tx, entry.account, entry.quantity, entry.barcode, inventory_flow)
+			if err != nil {
+				return err
+			}
+			if insert_into_inventory && costs.IsZero() {
+				err = tx.insert_inventory(entry)
+				if err != nil {
+					return err
+				}
 			}
 		}
 	}
+	if insert_into_journal {
+		first := array_of_journal_tag[0]
+		err = tx.insert_entry_header(entry_header{entry_number, first.date, first.description, first.name, first.employee_name, first.entry_date})
+		if err != nil {
+			return err
+		}
+		return s.update_snapshots(tx, array_of_journal_tag)
+	}
+	return nil
 }
 
-func (s Financial_accounting) cost_flow(account string, quantity float64, barcode string, insert bool) float64 {
+func (s Financial_accounting) cost_flow(tx storage, account string, quantity decimal.Decimal, barcode string, insert bool) (decimal.Decimal, error) {
 	var order_by_date_asc_or_desc string
 	switch {
-	case quantity > 0:
-		return 0
+	case quantity.IsPositive():
+		return decimal.Zero, nil
 	case s.return_cost_flow_type(account) == "fifo":
 		order_by_date_asc_or_desc = "asc"
 	case s.return_cost_flow_type(account) == "lifo":
 		order_by_date_asc_or_desc = "desc"
 	case s.return_cost_flow_type(account) == "wma":
-		weighted_average([]string{account})
+		err := weighted_average(tx, []string{account})
+		if err != nil {
+			return decimal.Zero, err
+		}
 		order_by_date_asc_or_desc = "asc"
 	default:
-		return 0
+		return decimal.Zero, nil
 	}
-	rows, _ := db.Query("select price,quantity from inventory where quantity>0 and account=? and barcode=? order by date "+order_by_date_asc_or_desc, account, barcode)
-	var inventory []journal_tag
-	for rows.Next() {
-		var tag journal_tag
-		rows.Scan(&tag.price, &tag.quantity)
-		inventory = append(inventory, tag)
+	inventory, err := tx.inventory_layers(account, barcode, order_by_date_asc_or_desc)
+	if err != nil {
+		return decimal.Zero, err
 	}
-	quantity = math.Abs(quantity)
+	quantity = quantity.Abs()
 	quantity_count := quantity
-	var costs float64
+	var costs decimal.Decimal
 	for _, item := range inventory {
-		if item.quantity > quantity_count {
-			costs += item.price * quantity_count
+		if item.quantity.GreaterThan(quantity_count) {
+			costs = costs.Add(item.price.Mul(quantity_count))
 			if insert {
-				db.Exec("update inventory set quantity=quantity-? where account=? and price=? and quantity=? and barcode=? order by date "+order_by_date_asc_or_desc+" limit 1", quantity_count, account, item.price, item.quantity, barcode)
+				err = tx.set_inventory_layer_quantity(item.id, item.quantity.Sub(quantity_count))
+				if err != nil {
+					return decimal.Zero, err
+				}
 			}
-			quantity_count = 0
+			quantity_count = decimal.Zero
 			break
 		}
-		if item.quantity <= quantity_count {
-			costs += item.price * item.quantity
-			if insert {
-				db.Exec("delete from inventory where account=? and price=? and quantity=? and barcode=? order by date "+order_by_date_asc_or_desc+" limit 1", account, item.price, item.quantity, barcode)
+		costs = costs.Add(item.price.Mul(item.quantity))
+		if insert {
+			err = tx.delete_inventory_layer(item.id)
+			if err != nil {
+				return decimal.Zero, err
 			}
-			quantity_count -= item.quantity
 		}
+		quantity_count = quantity_count.Sub(item.quantity)
 	}
-	if quantity_count != 0 {
-		log.Panic("you order ", quantity, " but you have ", quantity-quantity_count, " ", account, " with barcode ", barcode)
+	if !quantity_count.IsZero() {
+		return decimal.Zero, ErrInsufficientInventory{account, barcode, quantity, quantity.Sub(quantity_count)}
 	}
-	return costs
+	return s.round_value(costs), nil
 }
 
-func (s Financial_accounting) statement(journal []journal_tag, start_date, end_date time.Time) (map[string]map[string]map[string]map[string]map[string]float64, map[string]map[string]map[string]map[string]float64) {
-	var one_simple_entry []journal_tag
-	var previous_entry_number int
-	var date time.Time
-	flow_statement := map[string]map[string]map[string]map[string]map[string]float64{}
-	nan_flow_statement := map[string]map[string]map[string]map[string]float64{}
-	for _, entry := range journal {
-		date = s.parse_date(entry.date)
-		if previous_entry_number != entry.entry_number {
-			s.sum_flow(date, start_date, one_simple_entry, flow_statement)
-			s.sum_values(date, start_date, one_simple_entry, nan_flow_statement)
-			one_simple_entry = []journal_tag{}
+// statement starts from the snapshot and adds the journal after it, every entry is counted by its own date
+func (s Financial_accounting) statement(snapshot []balance_snapshot, journal []journal_tag, start_date, end_date time.Time) (map[string]map[string]map[string]map[string]map[string]decimal.Decimal, map[string]map[string]map[string]map[string]decimal.Decimal, error) {
+	flow_statement := map[string]map[string]map[string]map[string]map[string]decimal.Decimal{}
+	nan_flow_statement := map[string]map[string]map[string]map[string]decimal.Decimal{}
+	err := s.sum_snapshot(snapshot, start_date, flow_statement, nan_flow_statement)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, one_entry := range group_by_entry_number(journal) {
+		date := one_entry[0].date
+		if !date.Before(end_date) {
+			continue
 		}
-		if date.Before(end_date) {
-			one_simple_entry = append(one_simple_entry, entry)
+		err = s.sum_flow(date, start_date, one_entry, flow_statement)
+		if err != nil {
+			return nil, nil, err
+		}
+		err = s.sum_values(date, start_date, one_entry, nan_flow_statement)
+		if err != nil {
+			return nil, nil, err
 		}
-		previous_entry_number = entry.entry_number
 	}
-	s.sum_flow(date, start_date, one_simple_entry, flow_statement)
-	s.sum_values(date, start_date, one_simple_entry, nan_flow_statement)
-	return flow_statement, nan_flow_statement
+	return flow_statement, nan_flow_statement, nil
 }
 
-func (s Financial_accounting) sum_values(date, start_date time.Time, one_simple_entry []journal_tag, nan_flow_statement map[string]map[string]map[string]map[string]float64) {
-	for _, b := range one_simple_entry {
+func (s Financial_accounting) sum_values(date, start_date time.Time, one_entry []journal_tag, nan_flow_statement map[string]map[string]map[string]map[string]decimal.Decimal) error {
+	for _, b := range one_entry {
+		is_credit, err := s.is_credit(b.account)
+		if err != nil {
+			return err
+		}
 		map_v1 := initialize_map_3(nan_flow_statement, b.account, b.name, "value")
 		map_q1 := initialize_map_3(nan_flow_statement, b.account, b.name, "quantity")
 		map_v2 := initialize_map_3(nan_flow_statement, s.retained_earnings, b.name, "value")
 		map_q2 := initialize_map_3(nan_flow_statement, s.retained_earnings, b.name, "quantity")
 		if date.Before(start_date) {
 			switch {
-			case s.is_father(s.retained_earnings, b.account) && s.is_credit(b.account):
-				map_v2["beginning_balance"] += b.value
-				map_q2["beginning_balance"] += b.quantity
-			case s.is_father(s.retained_earnings, b.account) && !s.is_credit(b.account):
-				map_v2["beginning_balance"] -= b.value
-				map_q2["beginning_balance"] -= b.quantity
+			case s.is_father(s.retained_earnings, b.account) && is_credit:
+				sum_decimal(map_v2, "beginning_balance", b.value)
+				sum_decimal(map_q2, "beginning_balance", b.quantity)
+			case s.is_father(s.retained_earnings, b.account) && !is_credit:
+				sum_decimal(map_v2, "beginning_balance", b.value.Neg())
+				sum_decimal(map_q2, "beginning_balance", b.quantity.Neg())
 			default:
-				map_v1["beginning_balance"] += b.value
-				map_q1["beginning_balance"] += b.quantity
+				sum_decimal(map_v1, "beginning_balance", b.value)
+				sum_decimal(map_q1, "beginning_balance", b.quantity)
 			}
 		}
 		if date.After(start_date) {
-			if b.value >= 0 {
-				map_v1["increase"] += math.Abs(b.value)
-				map_q1["increase"] += math.Abs(b.quantity)
+			if !b.value.IsNegative() {
+				sum_decimal(map_v1, "increase", b.value.Abs())
+				sum_decimal(map_q1, "increase", b.quantity.Abs())
 			} else {
-				map_v1["decrease"] += math.Abs(b.value)
-				map_q1["decrease"] += math.Abs(b.quantity)
+				sum_decimal(map_v1, "decrease", b.value.Abs())
+				sum_decimal(map_q1, "decrease", b.quantity.Abs())
 			}
 		}
 	}
+	return nil
 }
 
-func (s Financial_accounting) sum_flow(date, start_date time.Time, one_simple_entry []journal_tag, flow_statement map[string]map[string]map[string]map[string]map[string]float64) {
-	for _, a := range one_simple_entry {
-		for _, b := range one_simple_entry {
-			map_v := initialize_map_4(flow_statement, a.account, b.account, b.name, "value")
-			map_q := initialize_map_4(flow_statement, a.account, b.account, b.name, "quantity")
-			if date.After(start_date) {
-				if b.account == a.account || s.is_credit(b.account) != s.is_credit(a.account) {
-					sum_flows(b, 1, map_v, map_q)
-				} else {
-					sum_flows(b, -1, map_v, map_q)
-				}
+func (s Financial_accounting) sum_flow(date, start_date time.Time, one_entry []journal_tag, flow_statement map[string]map[string]map[string]map[string]map[string]decimal.Decimal) error {
+	flows, err := s.entry_flows(one_entry)
+	if err != nil {
+		return err
+	}
+	for _, flow := range flows {
+		a, b := flow[0], flow[1]
+		map_v := initialize_map_4(flow_statement, a.account, b.account, b.name, "value")
+		map_q := initialize_map_4(flow_statement, a.account, b.account, b.name, "quantity")
+		if date.After(start_date) {
+			same_side, err := s.same_side(a.account, b.account)
+			if err != nil {
+				return err
+			}
+			if b.account == a.account || !same_side {
+				sum_flows(b, 1, map_v, map_q)
+			} else {
+				sum_flows(b, -1, map_v, map_q)
 			}
 		}
 	}
+	return nil
 }
 
-func (s Financial_accounting) prepare_statement(statement map[string]map[string]map[string]map[string]map[string]float64) {
+func (s Financial_accounting) prepare_statement(statement map[string]map[string]map[string]map[string]map[string]decimal.Decimal) {
 	for key_account_flow, map_account_flow := range statement {
 		if key_account_flow == s.cash_and_cash_equivalents {
 			for key_account, map_account := range map_account_flow {
@@ -642,40 +921,45 @@ func (s Financial_accounting) prepare_statement(statement map[string]map[string]
 								map_vpq1["turnover_days"] = statement[s.sales][key_account][key_name][key_vpq]["turnover_days"]
 							}
 						}
+						// the contra accounts are shown as deductions from their fathers
+						if s.is_contra(key_account) {
+							for _, key_number := range []string{"beginning_balance", "increase", "decrease", "increase_or_decrease", "ending_balance", "average", "percent"} {
+								map_vpq1[key_number] = map_vpq1[key_number].Neg()
+							}
+						}
 					}
 				}
 			}
 		}
 	}
-}
-
-func (s Financial_accounting) parse_date(string_date string) time.Time {
-	for _, i := range s.date_layout {
-		date, err := time.Parse(i, string_date)
-		if err == nil {
-			return date
+	// the flow of cash with the account that has the category has the flows of its sons already
+	for _, a := range s.accounts {
+		if a.cash_flow_category == "" {
+			continue
 		}
-	}
-	return time.Time{}
-}
-
-func (s Financial_accounting) is_father(father, name string) bool {
-	var last_name string
-	for {
-		for _, a := range s.accounts {
-			if a.name == name {
-				name = a.father
-			}
-			if father == name {
-				return true
+		for key_name, map_name := range statement[s.cash_and_cash_equivalents][a.name] {
+			for key_vpq, map_vpq := range map_name {
+				map_vpq1 := initialize_map_4(statement, "cash_flow", a.cash_flow_category, key_name, key_vpq)
+				for _, key_number := range []string{"inflow", "outflow", "flow"} {
+					sum_decimal(map_vpq1, key_number, map_vpq[key_number])
+				}
 			}
 		}
-		if last_name == name {
-			break
+	}
+	// the balances of the accounts that have a tax category are summed by the category, the sons are in the balance of the account already
+	for _, a := range s.accounts {
+		if a.tax_category == "" {
+			continue
+		}
+		for key_name, map_name := range statement[s.cash_and_cash_equivalents][a.name] {
+			for key_vpq, map_vpq := range map_name {
+				map_vpq1 := initialize_map_4(statement, "tax_category", a.tax_category, key_name, key_vpq)
+				for _, key_number := range []string{"beginning_balance", "increase", "decrease", "increase_or_decrease", "ending_balance"} {
+					sum_decimal(map_vpq1, key_number, map_vpq[key_number])
+				}
+			}
 		}
-		last_name = name
 	}
-	return false
 }
 
 func (s Financial_accounting) return_cost_flow_type(name string) string {
@@ -687,31 +971,68 @@ func (s Financial_accounting) return_cost_flow_type(name string) string {
 	return ""
 }
 
-func (s Financial_accounting) is_credit(name string) bool {
+func (s Financial_accounting) is_credit(name string) (bool, error) {
+	for _, a := range s.accounts {
+		if a.name == name {
+			return a.is_credit, nil
+		}
+	}
+	return false, ErrUnknownAccount{name}
+}
+
+// same_side is true if both accounts are debit accounts or both are credit accounts
+func (s Financial_accounting) same_side(account, other_account string) (bool, error) {
+	is_credit, err := s.is_credit(account)
+	if err != nil {
+		return false, err
+	}
+	other_is_credit, err := s.is_credit(other_account)
+	if err != nil {
+		return false, err
+	}
+	return is_credit == other_is_credit, nil
+}
+
+func (s Financial_accounting) is_account(name string) bool {
 	for _, a := range s.accounts {
 		if a.name == name {
-			return a.is_credit
+			return true
 		}
 	}
-	log.Panic(name, " is not debit nor credit")
 	return false
 }
 
-func (s Financial_accounting) check_debit_equal_credit(array_of_entry []Account_value_quantity_barcode, check_one_debit_and_one_credit bool) ([]Account_value_quantity_barcode, []Account_value_quantity_barcode) {
+func (s Financial_accounting) check_known_accounts(array_of_entry []Account_value_quantity_barcode) error {
+	for _, entry := range array_of_entry {
+		if !s.is_account(entry.Account) {
+			return ErrUnknownAccount{entry.Account}
+		}
+		if s.is_inactive(entry.Account) {
+			return ErrInactiveAccount{entry.Account}
+		}
+	}
+	return nil
+}
+
+func (s Financial_accounting) check_debit_equal_credit(array_of_entry []Account_value_quantity_barcode, check_one_debit_and_one_credit bool) ([]Account_value_quantity_barcode, []Account_value_quantity_barcode, error) {
 	var debit_entries, credit_entries []Account_value_quantity_barcode
-	var zero float64
+	var zero decimal.Decimal
 	for _, entry := range array_of_entry {
-		switch s.is_credit(entry.Account) {
+		is_credit, err := s.is_credit(entry.Account)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch is_credit {
 		case false:
-			zero += entry.value
-			if entry.value >= 0 {
+			zero = zero.Add(entry.value)
+			if !entry.value.IsNegative() {
 				debit_entries = append(debit_entries, entry)
 			} else {
 				credit_entries = append(credit_entries, entry)
 			}
 		case true:
-			zero -= entry.value
-			if entry.value <= 0 {
+			zero = zero.Sub(entry.value)
+			if !entry.value.IsPositive() {
 				debit_entries = append(debit_entries, entry)
 			} else {
 				credit_entries = append(credit_entries, entry)
@@ -721,42 +1042,102 @@ func (s Financial_accounting) check_debit_equal_credit(array_of_entry []Account_
 	len_debit_entries := len(debit_entries)
 	len_credit_entries := len(credit_entries)
 	if (len_debit_entries != 1) && (len_credit_entries != 1) {
-		log.Panic("should be one credit or one debit in the entry ", array_of_entry)
+		return nil, nil, ErrInvalidEntry{array_of_entry, "should be one credit or one debit in the entry"}
 	}
 	if !((len_debit_entries == 1) && (len_credit_entries == 1)) && check_one_debit_and_one_credit {
-		log.Panic("should be one credit and one debit in the entry ", array_of_entry)
+		return nil, nil, ErrInvalidEntry{array_of_entry, "should be one credit and one debit in the entry"}
 	}
-	if zero != 0 {
-		log.Panic(zero, " not equal 0 if the number>0 it means debit overstated else credit overstated debit-credit should equal zero ", array_of_entry)
+	if !zero.IsZero() {
+		return nil, nil, ErrUnbalancedEntry{zero, array_of_entry}
 	}
-	return debit_entries, credit_entries
+	return debit_entries, credit_entries, nil
 }
 
-func (s Financial_accounting) convert_to_simple_entry(debit_entries, credit_entries []Account_value_quantity_barcode) [][]Account_value_quantity_barcode {
-	simple_entries := [][]Account_value_quantity_barcode{}
-	for _, debit_entry := range debit_entries {
-		for _, credit_entry := range credit_entries {
-			simple_entries = append(simple_entries, []Account_value_quantity_barcode{debit_entry, credit_entry})
+// balance_the_periods makes the lines of every period of the adjusted entry balance, the lines are rounded one by one
+// so the biggest line of the period takes the difference, it is the line that is alone on its side
+func (s Financial_accounting) balance_the_periods(periods [][]journal_tag) error {
+	for _, period := range periods {
+		var difference decimal.Decimal
+		biggest := 0
+		for index, line := range period {
+			is_credit, err := s.is_credit(line.account)
+			if err != nil {
+				return err
+			}
+			if is_credit {
+				difference = difference.Sub(line.value)
+			} else {
+				difference = difference.Add(line.value)
+			}
+			if line.value.Abs().GreaterThan(period[biggest].value.Abs()) {
+				biggest = index
+			}
+		}
+		if difference.IsZero() {
+			continue
+		}
+		line := &period[biggest]
+		is_credit, err := s.is_credit(line.account)
+		if err != nil {
+			return err
+		}
+		if is_credit {
+			line.value = line.value.Add(difference)
+		} else {
+			line.value = line.value.Sub(difference)
+		}
+		if !line.price.IsZero() {
+			line.quantity = s.round_quantity(line.value.Div(line.price))
 		}
 	}
-	for _, a := range simple_entries {
-		switch math.Abs(a[0].value) >= math.Abs(a[1].value) {
-		case true:
-			sign := a[0].value / a[1].value
-			price := a[0].value / a[0].quantity
-			a[0].value = a[1].value * sign / math.Abs(sign)
-			a[0].quantity = a[0].value / price
-		case false:
-			sign := a[0].value / a[1].value
-			price := a[1].value / a[1].quantity
-			a[1].value = a[0].value * sign / math.Abs(sign)
-			a[1].quantity = a[1].value / price
+	return nil
+}
+
+// entry_flows returns the lines of one entry as every line sees them, a line sees itself as it is and sees the lines
+// of the other side by the share of its value in its side. the entry has one line on one of its sides so the line that
+// is alone sees the other lines as they are, and the other lines see it by their values like the simple entries did
+func (s Financial_accounting) entry_flows(one_entry []journal_tag) ([][2]journal_tag, error) {
+	totals := map[bool]decimal.Decimal{}
+	sides := make([]bool, len(one_entry))
+	for index, line := range one_entry {
+		is_debit, err := s.is_debit(line)
+		if err != nil {
+			return nil, err
 		}
+		sides[index] = is_debit
+		totals[is_debit] = totals[is_debit].Add(line.value.Abs())
+	}
+	var flows [][2]journal_tag
+	for indexa, a := range one_entry {
+		for indexb, b := range one_entry {
+			switch {
+			case indexa == indexb:
+			case sides[indexa] == sides[indexb]:
+				continue
+			default:
+				share := divide(a.value.Abs(), totals[sides[indexa]])
+				b.value = s.round_value(b.value.Mul(share))
+				b.quantity = s.round_quantity(b.quantity.Mul(share))
+			}
+			flows = append(flows, [2]journal_tag{a, b})
+		}
+	}
+	return flows, nil
+}
+
+// is_debit is true for the lines that increase the accounts on the debit side or decrease the accounts on the credit side
+func (s Financial_accounting) is_debit(line journal_tag) (bool, error) {
+	is_credit, err := s.is_credit(line.account)
+	if err != nil {
+		return false, err
 	}
-	return simple_entries
+	if is_credit {
+		return !line.value.IsPositive(), nil
+	}
+	return !line.value.IsNegative(), nil
 }
 
-func (s Financial_accounting) analysis(statement map[string]map[string]map[string]map[string]map[string]float64) financial_analysis_statement {
+func (s Financial_accounting) analysis(statement map[string]map[string]map[string]map[string]map[string]decimal.Decimal) financial_analysis_statement {
 	return financial_analysis{
 		current_assets:                      statement[s.cash_and_cash_equivalents][s.current_assets]["names"]["value"]["ending_balance"],
 		current_liabilities:                 statement[s.cash_and_cash_equivalents][s.current_liabilities]["names"]["value"]["ending_balance"],
@@ -771,101 +1152,91 @@ func (s Financial_accounting) analysis(statement map[string]map[string]map[strin
 		net_sales:                           statement[s.cash_and_cash_equivalents][s.sales]["names"]["value"]["ending_balance"],
 		average_assets:                      statement[s.cash_and_cash_equivalents][s.assets]["names"]["value"]["average"],
 		average_equity:                      statement[s.cash_and_cash_equivalents][s.equity]["names"]["value"]["average"],
-		preferred_dividends:                 0,
-		average_common_stockholders_equity:  0,
-		market_price_per_shares_outstanding: 0,
+		preferred_dividends:                 decimal.Zero,
+		average_common_stockholders_equity:  decimal.Zero,
+		market_price_per_shares_outstanding: decimal.Zero,
 		cash_dividends:                      statement[s.cash_and_cash_equivalents][s.dividends]["names"]["value"]["flow"],
 		total_debt:                          statement[s.cash_and_cash_equivalents][s.liabilities]["names"]["value"]["ending_balance"],
 		total_assets:                        statement[s.cash_and_cash_equivalents][s.assets]["names"]["value"]["ending_balance"],
 		ebitda:                              statement[s.cash_and_cash_equivalents][s.ebitda]["names"]["value"]["ending_balance"],
 		interest_expense:                    statement[s.cash_and_cash_equivalents][s.interest_expense]["names"]["value"]["ending_balance"],
-		weighted_average_common_shares_outstanding: 0,
+		weighted_average_common_shares_outstanding: decimal.Zero,
 	}.financial_analysis_statement()
 }
 
-func (s Financial_accounting) sum_1st_column(statement map[string]map[string]map[string]map[string]map[string]float64) map[string]map[string]map[string]map[string]map[string]float64 {
-	new_statement := map[string]map[string]map[string]map[string]map[string]float64{}
-	var flow_accounts []string
-	for _, a := range s.accounts {
-		for _, b := range s.accounts {
-			if s.is_father(a.name, b.name) {
-				flow_accounts = append(flow_accounts, b.name)
+func (s Financial_accounting) sum_1st_column(statement map[string]map[string]map[string]map[string]map[string]decimal.Decimal) (map[string]map[string]map[string]map[string]map[string]decimal.Decimal, error) {
+	new_statement := map[string]map[string]map[string]map[string]map[string]decimal.Decimal{}
+	tree := s.chart_tree()
+	for key_account_flow, map_account_flow := range statement {
+		for _, father := range tree.paths[key_account_flow] {
+			same_side, err := s.same_side(father, key_account_flow)
+			if err != nil {
+				return nil, err
 			}
-		}
-		for key_account_flow, map_account_flow := range statement {
-			if IS_IN(key_account_flow, flow_accounts) {
-				for key_account, map_account := range map_account_flow {
-					for key_name, map_name := range map_account {
-						for key_vpq, map_vpq := range map_name {
-							map_vpq1 := initialize_map_4(new_statement, a.name, key_account, key_name, key_vpq)
-							for key_number, number := range map_vpq {
-								switch {
-								case IS_IN(key_number, []string{"inflow", "outflow"}):
-									if s.is_credit(a.name) == s.is_credit(key_account_flow) {
-										map_vpq1[key_number] += number
-									} else {
-										map_vpq1[key_number] -= number
-									}
-								default:
-									map_vpq1[key_number] = number
+			for key_account, map_account := range map_account_flow {
+				for key_name, map_name := range map_account {
+					for key_vpq, map_vpq := range map_name {
+						map_vpq1 := initialize_map_4(new_statement, father, key_account, key_name, key_vpq)
+						for key_number, number := range map_vpq {
+							switch {
+							case IS_IN(key_number, []string{"inflow", "outflow"}):
+								if same_side {
+									sum_decimal(map_vpq1, key_number, number)
+								} else {
+									sum_decimal(map_vpq1, key_number, number.Neg())
 								}
+							default:
+								map_vpq1[key_number] = number
 							}
 						}
 					}
 				}
 			}
 		}
-		flow_accounts = []string{}
 	}
-	return new_statement
+	return new_statement, nil
 }
 
-func (s Financial_accounting) sum_2nd_column(statement map[string]map[string]map[string]map[string]map[string]float64) map[string]map[string]map[string]map[string]map[string]float64 {
-	new_statement := map[string]map[string]map[string]map[string]map[string]float64{}
+func (s Financial_accounting) sum_2nd_column(statement map[string]map[string]map[string]map[string]map[string]decimal.Decimal) (map[string]map[string]map[string]map[string]map[string]decimal.Decimal, error) {
+	new_statement := map[string]map[string]map[string]map[string]map[string]decimal.Decimal{}
+	tree := s.chart_tree()
 	for key_account_flow, map_account_flow := range statement {
-		for key_account, map_account := range map_account_flow {
-			var last_name string
-			key1 := key_account
-			for {
-				for _, ss := range s.accounts {
-					if ss.name == key_account {
-						key_account = ss.father
-						for key_name, map_name := range map_account {
-							for key_vpq, map_vpq := range map_name {
-								map_vpq1 := initialize_map_4(new_statement, key_account_flow, ss.name, key_name, key_vpq)
-								for key_number, number := range map_vpq {
-									switch {
-									case !IS_IN(key_number, []string{"inflow", "outflow"}):
-										if s.is_credit(key1) == s.is_credit(ss.name) {
-											map_vpq1[key_number] += number
-										} else {
-											map_vpq1[key_number] -= number
-										}
-									case key_account_flow != key1:
-										map_vpq1[key_number] += number
-									case key_account_flow == ss.name:
-										new_statement[key_account_flow][key1][key_name][key_vpq][key_number] += number
-									}
+		for key1, map_account := range map_account_flow {
+			for _, father := range tree.paths[key1] {
+				same_side, err := s.same_side(key1, father)
+				if err != nil {
+					return nil, err
+				}
+				for key_name, map_name := range map_account {
+					for key_vpq, map_vpq := range map_name {
+						map_vpq1 := initialize_map_4(new_statement, key_account_flow, father, key_name, key_vpq)
+						for key_number, number := range map_vpq {
+							switch {
+							case !IS_IN(key_number, []string{"inflow", "outflow"}):
+								if same_side {
+									sum_decimal(map_vpq1, key_number, number)
+								} else {
+									sum_decimal(map_vpq1, key_number, number.Neg())
 								}
+							case key_account_flow != key1:
+								sum_decimal(map_vpq1, key_number, number)
+							case key_account_flow == father:
+								sum_decimal(new_statement[key_account_flow][key1][key_name][key_vpq], key_number, number)
 							}
 						}
 					}
 				}
-				if last_name == key_account {
-					break
-				}
-				last_name = key_account
 			}
 		}
 	}
-	return new_statement
+	return new_statement, nil
 }
 
-func sum_3rd_column(statement map[string]map[string]map[string]map[string]map[string]float64, names, exempt_names []string, name string, in_names bool) {
+func sum_3rd_column(statement map[string]map[string]map[string]map[string]map[string]decimal.Decimal, names, exempt_names []string, name string, in_names bool) {
 	for _, map_account_flow := range statement {
 		for _, map_account := range map_account_flow {
 			if map_account[name] == nil {
-				map_account[name] = map[string]map[string]float64{}
+				map_account[name] = map[string]map[string]decimal.Decimal{}
 			}
 			for key_name, map_name := range map_account {
 				var ok bool
@@ -876,10 +1247,10 @@ func sum_3rd_column(statement map[string]map[string]map[string]map[string]map[st
 					if ok {
 						for key_vpq, map_vpq := range map_name {
 							if map_account[name][key_vpq] == nil {
-								map_account[name][key_vpq] = map[string]float64{}
+								map_account[name][key_vpq] = map[string]decimal.Decimal{}
 							}
 							for key_number, number := range map_vpq {
-								map_account[name][key_vpq][key_number] += number
+								sum_decimal(map_account[name][key_vpq], key_number, number)
 							}
 						}
 					}
@@ -889,7 +1260,7 @@ func sum_3rd_column(statement map[string]map[string]map[string]map[string]map[st
 	}
 }
 
-func combine_statements(flow_statement map[string]map[string]map[string]map[string]map[string]float64, nan_flow_statement map[string]map[string]map[string]map[string]float64) map[string]map[string]map[string]map[string]map[string]float64 {
+func combine_statements(flow_statement map[string]map[string]map[string]map[string]map[string]decimal.Decimal, nan_flow_statement map[string]map[string]map[string]map[string]decimal.Decimal) map[string]map[string]map[string]map[string]map[string]decimal.Decimal {
 	for key_account_flow, _ := range nan_flow_statement {
 		for key_account, map_account := range nan_flow_statement {
 			for key_name, map_name := range map_account {
@@ -905,63 +1276,63 @@ func combine_statements(flow_statement map[string]map[string]map[string]map[stri
 	return flow_statement
 }
 
-func sum_flows(b journal_tag, x float64, map_v, map_q map[string]float64) {
-	if b.value*x < 0 {
-		map_v["outflow"] += math.Abs(b.value)
-		map_q["outflow"] += math.Abs(b.quantity)
+func sum_flows(b journal_tag, x int, map_v, map_q map[string]decimal.Decimal) {
+	if b.value.Sign()*x < 0 {
+		sum_decimal(map_v, "outflow", b.value.Abs())
+		sum_decimal(map_q, "outflow", b.quantity.Abs())
 	} else {
-		map_v["inflow"] += math.Abs(b.value)
-		map_q["inflow"] += math.Abs(b.quantity)
+		sum_decimal(map_v, "inflow", b.value.Abs())
+		sum_decimal(map_q, "inflow", b.quantity.Abs())
 	}
 }
 
-func ending_balance(statement map[string]map[string]map[string]map[string]map[string]float64, key_account_flow, key_account, key_name, key_vpq string) float64 {
-	return statement[key_account_flow][key_account][key_name][key_vpq]["beginning_balance"] + statement[key_account][key_account][key_name][key_vpq]["increase"] - statement[key_account][key_account][key_name][key_vpq]["decrease"]
+func ending_balance(statement map[string]map[string]map[string]map[string]map[string]decimal.Decimal, key_account_flow, key_account, key_name, key_vpq string) decimal.Decimal {
+	return statement[key_account_flow][key_account][key_name][key_vpq]["beginning_balance"].Add(statement[key_account][key_account][key_name][key_vpq]["increase"]).Sub(statement[key_account][key_account][key_name][key_vpq]["decrease"])
 }
 
-func vertical_analysis(statement map[string]map[string]map[string]map[string]map[string]float64, days float64) {
+func vertical_analysis(statement map[string]map[string]map[string]map[string]map[string]decimal.Decimal, days decimal.Decimal) {
 	for key_account_flow, map_account_flow := range statement {
 		for key_account, map_account := range map_account_flow {
 			for key_name, map_name := range map_account {
 				for key_vpq, map_vpq := range map_name {
-					map_vpq["increase_or_decrease"] = map_vpq["increase"] - map_vpq["decrease"]
-					map_vpq["ending_balance"] = map_vpq["beginning_balance"] + map_vpq["increase_or_decrease"]
-					map_vpq["flow"] = map_vpq["inflow"] - map_vpq["outflow"]
-					map_vpq["average"] = (map_vpq["ending_balance"] + map_vpq["beginning_balance"]) / 2
-					map_vpq["turnover"] = map_vpq["inflow"] / map_vpq["average"]
-					map_vpq["turnover_days"] = days / map_vpq["turnover"]
-					map_vpq["growth_ratio"] = map_vpq["ending_balance"] / map_vpq["beginning_balance"]
-					map_vpq["percent"] = map_vpq["ending_balance"] / ending_balance(statement, key_account_flow, key_account_flow, key_name, key_vpq)
-					map_vpq["name_percent"] = map_vpq["ending_balance"] / ending_balance(statement, key_account_flow, key_account, "all", key_vpq)
+					map_vpq["increase_or_decrease"] = map_vpq["increase"].Sub(map_vpq["decrease"])
+					map_vpq["ending_balance"] = map_vpq["beginning_balance"].Add(map_vpq["increase_or_decrease"])
+					map_vpq["flow"] = map_vpq["inflow"].Sub(map_vpq["outflow"])
+					map_vpq["average"] = map_vpq["ending_balance"].Add(map_vpq["beginning_balance"]).Div(decimal.NewFromInt(2))
+					map_vpq["turnover"] = divide(map_vpq["inflow"], map_vpq["average"])
+					map_vpq["turnover_days"] = divide(days, map_vpq["turnover"])
+					map_vpq["growth_ratio"] = divide(map_vpq["ending_balance"], map_vpq["beginning_balance"])
+					map_vpq["percent"] = divide(map_vpq["ending_balance"], ending_balance(statement, key_account_flow, key_account_flow, key_name, key_vpq))
+					map_vpq["name_percent"] = divide(map_vpq["ending_balance"], ending_balance(statement, key_account_flow, key_account, "all", key_vpq))
 				}
 			}
 		}
 	}
 }
 
-func horizontal_analysis(statement_current, statement_base map[string]map[string]map[string]map[string]map[string]float64) {
+func horizontal_analysis(statement_current, statement_base map[string]map[string]map[string]map[string]map[string]decimal.Decimal) {
 	for key_account_flow, map_account_flow := range statement_current {
 		for key_account, map_account := range map_account_flow {
 			for key_name, map_name := range map_account {
 				for key_vpq, map_vpq := range map_name {
-					map_vpq["change_since_base_period"] = map_vpq["ending_balance"] - statement_base[key_account_flow][key_account][key_name][key_vpq]["ending_balance"]
-					map_vpq["growth_ratio_to_base_period"] = map_vpq["ending_balance"] / statement_base[key_account_flow][key_account][key_name][key_vpq]["ending_balance"]
+					map_vpq["change_since_base_period"] = map_vpq["ending_balance"].Sub(statement_base[key_account_flow][key_account][key_name][key_vpq]["ending_balance"])
+					map_vpq["growth_ratio_to_base_period"] = divide(map_vpq["ending_balance"], statement_base[key_account_flow][key_account][key_name][key_vpq]["ending_balance"])
 				}
 			}
 		}
 	}
 }
 
-func calculate_price(statement map[string]map[string]map[string]map[string]map[string]float64) {
+func calculate_price(statement map[string]map[string]map[string]map[string]map[string]decimal.Decimal) {
 	for _, map_account_flow := range statement {
 		for _, map_account := range map_account_flow {
 			for _, map_name := range map_account {
 				if map_name["price"] == nil {
-					map_name["price"] = map[string]float64{}
+					map_name["price"] = map[string]decimal.Decimal{}
 				}
 				for _, map_vpq := range map_name {
 					for key_number, _ := range map_vpq {
-						map_name["price"][key_number] = map_name["value"][key_number] / map_name["quantity"][key_number]
+						map_name["price"][key_number] = divide(map_name["value"][key_number], map_name["quantity"][key_number])
 					}
 				}
 			}
@@ -969,36 +1340,36 @@ func calculate_price(statement map[string]map[string]map[string]map[string]map[s
 	}
 }
 
-func initialize_map_4(m map[string]map[string]map[string]map[string]map[string]float64, a, b, c, d string) map[string]float64 {
+func initialize_map_4(m map[string]map[string]map[string]map[string]map[string]decimal.Decimal, a, b, c, d string) map[string]decimal.Decimal {
 	if m[a] == nil {
-		m[a] = map[string]map[string]map[string]map[string]float64{}
+		m[a] = map[string]map[string]map[string]map[string]decimal.Decimal{}
 	}
 	if m[a][b] == nil {
-		m[a][b] = map[string]map[string]map[string]float64{}
+		m[a][b] = map[string]map[string]map[string]decimal.Decimal{}
 	}
 	if m[a][b][c] == nil {
-		m[a][b][c] = map[string]map[string]float64{}
+		m[a][b][c] = map[string]map[string]decimal.Decimal{}
 	}
 	if m[a][b][c][d] == nil {
-		m[a][b][c][d] = map[string]float64{}
+		m[a][b][c][d] = map[string]decimal.Decimal{}
 	}
 	return m[a][b][c][d]
 }
 
-func initialize_map_3(m map[string]map[string]map[string]map[string]float64, a, b, c string) map[string]float64 {
+func initialize_map_3(m map[string]map[string]map[string]map[string]decimal.Decimal, a, b, c string) map[string]decimal.Decimal {
 	if m[a] == nil {
-		m[a] = map[string]map[string]map[string]float64{}
+		m[a] = map[string]map[string]map[string]decimal.Decimal{}
 	}
 	if m[a][b] == nil {
-		m[a][b] = map[string]map[string]float64{}
+		m[a][b] = map[string]map[string]decimal.Decimal{}
 	}
 	if m[a][b][c] == nil {
-		m[a][b][c] = map[string]float64{}
+		m[a][b][c] = map[string]decimal.Decimal{}
 	}
 	return m[a][b][c]
 }
 
-func adjuste_the_array(entry_expair time.Time, date time.Time, array_day_start_end []day_start_end, array_to_insert []journal_tag, adjusting_method string, description string, name string, employee_name string) [][]journal_tag {
+func (s Financial_accounting) adjuste_the_array(entry_expair time.Time, date time.Time, array_day_start_end []day_start_end, array_to_insert []journal_tag, adjusting_method string, description string, name string, employee_name string) ([][]journal_tag, error) {
 	var day_start_end_date_minutes_array []day_start_end_date_minutes
 	var total_minutes float64
 	var previous_end_date, end time.Time
@@ -1010,10 +1381,10 @@ func adjuste_the_array(entry_expair time.Time, date time.Time, array_day_start_e
 				previous_end_date = end
 				end = time.Date(year, month_sting, day+day_counter, element.end_hour, element.end_minute, 0, 0, time.Local)
 				if start.After(end) {
-					log.Panic("the start_hour and start_minute should be smaller than end_hour and end_minute for ", element)
+					return nil, ErrInvalidSchedule{fmt.Sprint("the start_hour and start_minute should be smaller than end_hour and end_minute for ", element)}
 				}
 				if previous_end_date.After(start) {
-					log.Panic("the end_hour and end_minute for ", element.day, " should be smaller than start_hour and start_minute for the second ", element)
+					return nil, ErrInvalidSchedule{fmt.Sprint("the end_hour and end_minute for ", element.day, " should be smaller than start_hour and start_minute for the second ", element)}
 				}
 				minutes := end.Sub(start).Minutes()
 				total_minutes += minutes
@@ -1021,68 +1392,72 @@ func adjuste_the_array(entry_expair time.Time, date time.Time, array_day_start_e
 			}
 		}
 	}
+	// the shape of the schedule is float but the values are rounded decimal and the last slot takes what left
+	// so the values of the schedule sum exactly to the value of the entry
 	var adjusted_array_to_insert [][]journal_tag
 	for _, entry := range array_to_insert {
-		var value, value_counter, second_counter float64
+		var value_counter decimal.Decimal
+		var second_counter float64
 		var one_account_adjusted_list []journal_tag
-		total_value := math.Abs(entry.value)
-		deprecation := math.Pow(total_value, 1/total_minutes)
-		value_per_second := entry.value / total_minutes
+		total_value := entry.value.Abs()
+		total_value_float := total_value.InexactFloat64()
+		deprecation := math.Pow(total_value_float, 1/total_minutes)
 		for index, element := range day_start_end_date_minutes_array {
+			var ratio float64
 			switch adjusting_method {
 			case "linear":
-				value = element.minutes * value_per_second
+				ratio = element.minutes / total_minutes
 			case "exponential":
-				value = math.Pow(deprecation, second_counter+element.minutes) - math.Pow(deprecation, second_counter)
+				ratio = (math.Pow(deprecation, second_counter+element.minutes) - math.Pow(deprecation, second_counter)) / total_value_float
 			case "logarithmic":
-				value = (total_value / math.Pow(deprecation, second_counter)) - (total_value / math.Pow(deprecation, second_counter+element.minutes))
+				ratio = ((total_value_float / math.Pow(deprecation, second_counter)) - (total_value_float / math.Pow(deprecation, second_counter+element.minutes))) / total_value_float
 			}
 			second_counter += element.minutes
 
-			quantity := value / entry.price
-			if index >= delta_days-1 {
-				value = math.Abs(total_value - value_counter)
-				quantity = value / entry.price
+			value := s.round_value(total_value.Mul(decimal.NewFromFloat(ratio)))
+			if index == len(day_start_end_date_minutes_array)-1 {
+				value = total_value.Sub(value_counter).Abs()
 			}
-			value_counter += math.Abs(value)
-			if entry.value < 0 {
-				value = -math.Abs(value)
+			quantity := s.round_quantity(divide(value, entry.price))
+			value_counter = value_counter.Add(value.Abs())
+			if entry.value.IsNegative() {
+				value = value.Abs().Neg()
 			}
-			if entry.quantity < 0 {
-				quantity = -math.Abs(quantity)
+			if entry.quantity.IsNegative() {
+				quantity = quantity.Abs().Neg()
 			}
 
 			one_account_adjusted_list = append(one_account_adjusted_list, journal_tag{
-				date:          element.start_date.String(),
+				date:          element.start_date,
 				entry_number:  0,
 				account:       entry.account,
 				value:         value,
 				price:         entry.price,
 				quantity:      quantity,
 				barcode:       entry.barcode,
-				entry_expair:  element.end_date.String(),
+				entry_expair:  element.end_date,
 				description:   description,
 				name:          name,
 				employee_name: employee_name,
-				entry_date:    Now.String(),
+				entry_date:    Now,
 				reverse:       false,
 			})
 		}
 		adjusted_array_to_insert = append(adjusted_array_to_insert, one_account_adjusted_list)
 	}
-	return adjusted_array_to_insert
+	return adjusted_array_to_insert, nil
 }
 
-func check_the_params(entry_expair time.Time, adjusting_method string, date time.Time, array_of_entry []Account_value_quantity_barcode, array_day_start_end []day_start_end) []day_start_end {
+func (s Financial_accounting) check_the_params(entry_expair time.Time, adjusting_method string, date time.Time, array_of_entry []Account_value_quantity_barcode, array_day_start_end []day_start_end) ([]day_start_end, error) {
 	if entry_expair.IsZero() == IS_IN(adjusting_method, adjusting_methods[:]) {
-		log.Panic("check entry_expair => ", entry_expair, " and adjusting_method => ", adjusting_method, " should be in ", adjusting_methods)
+		return nil, ErrInvalidSchedule{fmt.Sprint("check entry_expair => ", entry_expair, " and adjusting_method => ", adjusting_method, " should be in ", adjusting_methods)}
 	}
-	if !entry_expair.IsZero() {
-		check_dates(date, entry_expair)
+	if !entry_expair.IsZero() && date.After(entry_expair) {
+		return nil, ErrInvalidSchedule{fmt.Sprint("the date ", date, " should be before entry_expair ", entry_expair)}
 	}
 	for _, entry := range array_of_entry {
-		if IS_IN(entry.Account, inventory) && !IS_IN(adjusting_method, []string{"expire", ""}) {
-			log.Panic(entry.Account + " is in inventory you just can use expire or make it empty")
+		if IS_IN(entry.Account, s.inventory_accounts) && !IS_IN(adjusting_method, []string{"expire", ""}) {
+			return nil, ErrInvalidSchedule{entry.Account + " is in inventory you just can use expire or make it empty"}
 		}
 	}
 	if IS_IN(adjusting_method, depreciation_methods[:]) {
@@ -1100,43 +1475,46 @@ func check_the_params(entry_expair time.Time, adjusting_method string, date time
 			array_day_start_end[index].day = strings.Title(element.day)
 			switch {
 			case !IS_IN(array_day_start_end[index].day, standard_days[:]):
-				log.Panic("error ", element.day, " for ", element, " is not in ", standard_days)
+				return nil, ErrInvalidSchedule{fmt.Sprint("error ", element.day, " for ", element, " is not in ", standard_days)}
 			case element.start_hour < 0:
-				log.Panic("error ", element.start_hour, " for ", element, " is < 0")
+				return nil, ErrInvalidSchedule{fmt.Sprint("error ", element.start_hour, " for ", element, " is < 0")}
 			case element.start_hour > 23:
-				log.Panic("error ", element.start_hour, " for ", element, " is > 23")
+				return nil, ErrInvalidSchedule{fmt.Sprint("error ", element.start_hour, " for ", element, " is > 23")}
 			case element.start_minute < 0:
-				log.Panic("error ", element.start_minute, " for ", element, " is < 0")
+				return nil, ErrInvalidSchedule{fmt.Sprint("error ", element.start_minute, " for ", element, " is < 0")}
 			case element.start_minute > 59:
-				log.Panic("error ", element.start_minute, " for ", element, " is > 59")
+				return nil, ErrInvalidSchedule{fmt.Sprint("error ", element.start_minute, " for ", element, " is > 59")}
 			case element.end_hour < 0:
-				log.Panic("error ", element.end_hour, " for ", element, " is < 0")
+				return nil, ErrInvalidSchedule{fmt.Sprint("error ", element.end_hour, " for ", element, " is < 0")}
 			case element.end_hour > 23:
-				log.Panic("error ", element.end_hour, " for ", element, " is > 23")
+				return nil, ErrInvalidSchedule{fmt.Sprint("error ", element.end_hour, " for ", element, " is > 23")}
 			case element.end_minute < 0:
-				log.Panic("error ", element.end_minute, " for ", element, " is < 0")
+				return nil, ErrInvalidSchedule{fmt.Sprint("error ", element.end_minute, " for ", element, " is < 0")}
 			case element.end_minute > 59:
-				log.Panic("error ", element.end_minute, " for ", element, " is > 59")
+				return nil, ErrInvalidSchedule{fmt.Sprint("error ", element.end_minute, " for ", element, " is > 59")}
 			}
 		}
 	}
-	return array_day_start_end
+	return array_day_start_end, nil
 }
 
-func find_barcode(array_of_entry []Account_value_quantity_barcode) {
+func find_barcode(tx storage, array_of_entry []Account_value_quantity_barcode) error {
 	for index, entry := range array_of_entry {
 		if entry.Account == "" && entry.barcode == "" {
-			log.Panic("can't find the account name if the barcode is empty in ", entry)
+			return ErrInvalidEntry{[]Account_value_quantity_barcode{entry}, "can't find the account name if the barcode is empty"}
 		}
-		var tag string
 		if entry.Account == "" {
-			err := db.QueryRow("select account from journal where barcode=? limit 1", entry.barcode).Scan(&tag)
+			tag, err := tx.account_of_barcode(entry.barcode)
+			if err == sql.ErrNoRows {
+				return ErrUnknownBarcode{entry.barcode}
+			}
 			if err != nil {
-				log.Panic("the barcode is wrong for ", entry)
+				return err
 			}
 			array_of_entry[index].Account = tag
 		}
 	}
+	return nil
 }
 
 func unpack_the_array(array_to_insert []journal_tag, adjusted_array_to_insert [][]journal_tag) []journal_tag {
@@ -1147,84 +1525,74 @@ func unpack_the_array(array_to_insert []journal_tag, adjusted_array_to_insert []
 	return array_to_insert
 }
 
-func insert_to_journal_tag(array_of_entry []Account_value_quantity_barcode, date time.Time, entry_expair time.Time, description string, name string, employee_name string) []journal_tag {
+func insert_to_journal_tag(array_of_entry []Account_value_quantity_barcode, date time.Time, entry_expair time.Time, description string, name string, employee_name string) ([]journal_tag, error) {
 	var array_to_insert []journal_tag
 	for _, entry := range array_of_entry {
-		price := entry.value / entry.quantity
-		if price < 0 {
-			log.Panic("the ", entry.value, " and ", entry.quantity, " for ", entry, " should be positive both or negative both")
+		price := divide(entry.value, entry.quantity)
+		if price.IsNegative() {
+			return nil, ErrInvalidEntry{[]Account_value_quantity_barcode{entry}, "the value and quantity should be positive both or negative both"}
 		}
 		array_to_insert = append(array_to_insert, journal_tag{
-			date:          date.String(),
+			date:          date,
 			entry_number:  0,
 			account:       entry.Account,
 			value:         entry.value,
 			price:         price,
 			quantity:      entry.quantity,
 			barcode:       entry.barcode,
-			entry_expair:  entry_expair.String(),
+			entry_expair:  entry_expair,
 			description:   description,
 			name:          name,
 			employee_name: employee_name,
-			entry_date:    Now.String(),
+			entry_date:    Now,
 			reverse:       false,
 		})
 	}
-	return array_to_insert
+	return array_to_insert, nil
 }
 
-func select_journal(entry_number uint, account string, start_date, end_date time.Time) []journal_tag {
-	var journal []journal_tag
-	var rows *sql.Rows
+// select_journal reads the lines of one entry or of one account between the dates, only one of them should be given
+func (s Financial_accounting) select_journal(entry_number uint, account string, start_date, end_date time.Time) ([]journal_tag, error) {
 	switch {
 	case entry_number != 0 && account == "":
-		rows, _ = db.Query("select * from journal where date>? and date<? and entry_number=? order by date", start_date.String(), end_date.String(), entry_number)
+		return s.db.journal_of_entry_number_between(entry_number, start_date, end_date)
 	case entry_number == 0 && account != "":
-		rows, _ = db.Query("select * from journal where date>? and date<? and account=? order by date", start_date.String(), end_date.String(), account)
-	default:
-		log.Panic("should be one of these entry_number != 0 && account == '' or entry_number == 0 && account != '' ")
+		return s.db.journal_of_account_between(account, start_date, end_date)
 	}
-	for rows.Next() {
-		var tag journal_tag
-		rows.Scan(&tag.date, &tag.entry_number, &tag.account, &tag.value, &tag.price, &tag.quantity, &tag.barcode, &tag.entry_expair, &tag.description, &tag.name, &tag.employee_name, &tag.entry_date, &tag.reverse)
-		journal = append(journal, tag)
-	}
-	return journal
+	return nil, ErrInvalidEntry{nil, "select_journal takes an entry_number or an account and not both"}
 }
 
-func weighted_average(array_of_accounts []string) {
+func weighted_average(tx storage, array_of_accounts []string) error {
 	for _, account := range array_of_accounts {
-		db.Exec("update inventory set price=(select sum(value)/sum(quantity) from journal where account=?) where account=?", account, account)
-	}
-}
-
-func entry_number() int {
-	var tag int
-	err := db.QueryRow("select max(entry_number) from journal").Scan(&tag)
-	if err != nil {
-		tag = 0
+		err := tx.weighted_average(account)
+		if err != nil {
+			return err
+		}
 	}
-	return tag + 1
+	return nil
 }
 
+// group_by_account_and_barcode keeps the lines in the order they are entered so the entry is saved as it is entered
 func group_by_account_and_barcode(array_of_entry []Account_value_quantity_barcode) []Account_value_quantity_barcode {
 	type Account_barcode struct {
 		Account, barcode string
 	}
 	g := map[Account_barcode]*Account_value_quantity_barcode{}
+	var keys []Account_barcode
 	for _, v := range array_of_entry {
 		key := Account_barcode{v.Account, v.barcode}
 		sums := g[key]
 		if sums == nil {
 			sums = &Account_value_quantity_barcode{}
 			g[key] = sums
+			keys = append(keys, key)
 		}
-		sums.value += v.value
-		sums.quantity += v.quantity
+		sums.value = sums.value.Add(v.value)
+		sums.quantity = sums.quantity.Add(v.quantity)
 	}
 	array_of_entry = []Account_value_quantity_barcode{}
-	for key, v := range g {
-		array_of_entry = append(array_of_entry, Account_value_quantity_barcode{key.Account, v.value, v.quantity, key.barcode})
+	for _, key := range keys {
+		array_of_entry = append(array_of_entry, Account_value_quantity_barcode{key.Account, g[key].value, g[key].quantity, key.barcode})
 	}
 	return array_of_entry
 }
@@ -1232,7 +1600,7 @@ func group_by_account_and_barcode(array_of_entry []Account_value_quantity_barcod
 func remove_zero_values(array_of_entry []Account_value_quantity_barcode) []Account_value_quantity_barcode {
 	var index int
 	for index < len(array_of_entry) {
-		if array_of_entry[index].value == 0 || array_of_entry[index].quantity == 0 {
+		if array_of_entry[index].value.IsZero() || array_of_entry[index].quantity.IsZero() {
 			// fmt.Println(array_of_entry[index], " is removed because one of the values is 0")
 			array_of_entry = append(array_of_entry[:index], array_of_entry[index+1:]...)
 		} else {
@@ -1242,17 +1610,6 @@ func remove_zero_values(array_of_entry []Account_value_quantity_barcode) []Accou
 	return array_of_entry
 }
 
-func change_account_name(name, new_name string) {
-	var tag string
-	err := db.QueryRow("select account from journal where account=? limit 1", new_name).Scan(&tag)
-	if err == nil {
-		log.Panic("you can't change the name of [", name, "] to [", new_name, "] as new name because it used")
-	} else {
-		db.Exec("update journal set account=? where account=?", new_name, name)
-		db.Exec("update inventory set account=? where account=?", new_name, name)
-	}
-}
-
 func IS_IN(element string, elements []string) bool {
 	for _, a := range elements {
 		if a == element {
@@ -1262,16 +1619,17 @@ func IS_IN(element string, elements []string) bool {
 	return false
 }
 
-func check_accounts(column, table, panic string, elements []string) {
-	results, err := db.Query("select " + column + " from " + table)
-	error_fatal(err)
-	for results.Next() {
-		var tag string
-		results.Scan(&tag)
+func (s Financial_accounting) check_accounts(tx storage, reason string, elements []string) error {
+	inventory_accounts, err := tx.inventory_accounts()
+	if err != nil {
+		return err
+	}
+	for _, tag := range inventory_accounts {
 		if !IS_IN(tag, elements) {
-			log.Panic(tag + panic)
+			return ErrInvalidConfig{tag + reason}
 		}
 	}
+	return nil
 }
 
 func error_fatal(err error) {
@@ -1280,13 +1638,14 @@ func error_fatal(err error) {
 	}
 }
 
-func check_dates(start_date, end_date time.Time) {
+func check_dates(start_date, end_date time.Time) error {
 	if start_date.After(end_date) {
-		log.Panic("please enter the start_date<=end_date")
+		return ErrInvalidPeriod{start_date, end_date}
 	}
+	return nil
 }
 
-func check_if_duplicates(slice_of_elements []string) {
+func duplicated_elements(slice_of_elements []string) []string {
 	var set_of_elems, duplicated_element []string
 	for _, element := range slice_of_elements {
 		for _, b := range set_of_elems {
@@ -1297,9 +1656,7 @@ func check_if_duplicates(slice_of_elements []string) {
 		}
 		set_of_elems = append(set_of_elems, element)
 	}
-	if len(duplicated_element) != 0 {
-		log.Panic(duplicated_element, " is duplicated values in the fields of Financial_accounting and that make error. you should remove the duplicate")
-	}
+	return duplicated_element
 }
 
 func concat(args ...interface{}) interface{} {
@@ -1314,11 +1671,11 @@ func concat(args ...interface{}) interface{} {
 	return v.Interface()
 }
 
-func discount_tax_calculator(price, discount_tax float64) float64 {
-	if discount_tax < 0 {
-		discount_tax = math.Abs(discount_tax)
-	} else if discount_tax > 0 {
-		discount_tax = price * discount_tax
+func discount_tax_calculator(price, discount_tax decimal.Decimal) decimal.Decimal {
+	if discount_tax.IsNegative() {
+		discount_tax = discount_tax.Abs()
+	} else if discount_tax.IsPositive() {
+		discount_tax = price.Mul(discount_tax)
 	}
 	return discount_tax
 }
@@ -1339,20 +1696,20 @@ func transpose(slice [][]journal_tag) [][]journal_tag {
 }
 
 func (s financial_analysis) financial_analysis_statement() financial_analysis_statement {
-	current_ratio := s.current_assets / s.current_liabilities
-	acid_test := (s.cash + s.short_term_investments + s.net_receivables) / s.current_liabilities
-	receivables_turnover := s.net_credit_sales / s.average_net_receivables
-	inventory_turnover := s.cost_of_goods_sold / s.average_inventory
-	profit_margin := s.net_income / s.net_sales
-	asset_turnover := s.net_sales / s.average_assets
-	return_on_assets := s.net_income / s.average_assets
-	return_on_equity := s.net_income / s.average_equity
-	payout_ratio := s.cash_dividends / s.net_income
-	debt_to_total_assets_ratio := s.total_debt / s.total_assets
-	times_interest_earned := s.ebitda / s.interest_expense
-	return_on_common_stockholders_equity := (s.net_income - s.preferred_dividends) / s.average_common_stockholders_equity
-	earnings_per_share := (s.net_income - s.preferred_dividends) / s.weighted_average_common_shares_outstanding
-	price_earnings_ratio := s.market_price_per_shares_outstanding / earnings_per_share
+	current_ratio := divide(s.current_assets, s.current_liabilities)
+	acid_test := divide(s.cash.Add(s.short_term_investments).Add(s.net_receivables), s.current_liabilities)
+	receivables_turnover := divide(s.net_credit_sales, s.average_net_receivables)
+	inventory_turnover := divide(s.cost_of_goods_sold, s.average_inventory)
+	profit_margin := divide(s.net_income, s.net_sales)
+	asset_turnover := divide(s.net_sales, s.average_assets)
+	return_on_assets := divide(s.net_income, s.average_assets)
+	return_on_equity := divide(s.net_income, s.average_equity)
+	payout_ratio := divide(s.cash_dividends, s.net_income)
+	debt_to_total_assets_ratio := divide(s.total_debt, s.total_assets)
+	times_interest_earned := divide(s.ebitda, s.interest_expense)
+	return_on_common_stockholders_equity := divide(s.net_income.Sub(s.preferred_dividends), s.average_common_stockholders_equity)
+	earnings_per_share := divide(s.net_income.Sub(s.preferred_dividends), s.weighted_average_common_shares_outstanding)
+	price_earnings_ratio := divide(s.market_price_per_shares_outstanding, earnings_per_share)
 	return financial_analysis_statement{
 		current_ratio:                        current_ratio,
 		acid_test:                            acid_test,
@@ -1698,43 +2055,55 @@ func main() {
 		invoice_discount:          "invoice_discount",
 		interest_expense:          "interest_expense",
 		accounts: []account{
-			{false, "wma", "", "assets"},
-			{false, "wma", "assets", "current_assets"},
-			{false, "", "current_assets", "cash_and_cash_equivalents"},
-			{false, "", "cash_and_cash_equivalents", "cash"},
-			{false, "wma", "current_assets", "short_term_investments"},
-			{false, "", "current_assets", "receivables"},
-			{false, "wma", "current_assets", "inventory"},
-			{false, "wma", "inventory", "book"},
-			{false, "wma", "inventory", "book1"},
-			{false, "fifo", "inventory", "panadol"},
-			{true, "", "", "liabilities"},
-			{true, "", "liabilities", "current_liabilities"},
-			{true, "", "current_liabilities", "tax"},
-			{true, "", "", "equity"},
-			{true, "", "equity", "retained_earnings"},
-			{true, "", "retained_earnings", "dividends"},
-			{true, "", "retained_earnings", "income_statement"},
-			{true, "", "income_statement", "Revenues"},
-			{true, "", "income_statement", "ebitda"},
-			{true, "", "ebitda", "sales"},
-			{true, "", "sales", "service revenue"},
-			{true, "", "sales", "revenue of book"},
-			{false, "", "ebitda", "expair_expenses"},
-			{false, "", "ebitda", "cost_of_goods_sold"},
-			{false, "", "cost_of_goods_sold", "cost of book"},
-			{false, "", "ebitda", "discounts"},
-			{false, "", "discounts", "discount of book"},
-			{false, "", "discounts", "invoice_discount"},
-			{false, "", "discounts", "service_discount"},
-			{false, "", "income_statement", "expenses"},
-			{false, "", "expenses", "interest_expense"},
-			{false, "", "expenses", "tax of book"},
-			{false, "", "expenses", "tax of service revenue"},
-			{false, "", "expenses", "invoice_tax"}},
-		Invoice_discounts_list: [][2]float64{{5, -10}},
-		auto_complete_entries: [][]account_method_value_price{{{"service revenue", "quantity_ratio", 0, 10}, {"tax of service revenue", "value", 1, 1}, {"tax", "value", 1, 1}, {"service_discount", "value", 1, 1}},
-			{{"book", "quantity_ratio", -1, 0}, {"revenue of book", "quantity_ratio", 1, 10}, {"cost of book", "copy_abs", 0, 0}, {"tax of book", "value", 1, 1}, {"tax", "value", 1, 1}, {"discount of book", "value", 1, 1}}},
+			{false, "wma", "", "assets", false, "1000", account_metadata{}},
+			{false, "wma", "assets", "current_assets", false, "1100", account_metadata{}},
+			{false, "", "current_assets", "cash_and_cash_equivalents", false, "1110", account_metadata{}},
+			{false, "", "cash_and_cash_equivalents", "cash", false, "1111", account_metadata{}},
+			{false, "wma", "current_assets", "short_term_investments", false, "1120", account_metadata{}},
+			{false, "", "current_assets", "receivables", false, "1130", account_metadata{}},
+			{false, "wma", "current_assets", "inventory", false, "1140", account_metadata{}},
+			{false, "wma", "inventory", "book", false, "1141", account_metadata{}},
+			{false, "wma", "inventory", "book1", false, "1142", account_metadata{}},
+			{false, "fifo", "inventory", "panadol", false, "1143", account_metadata{}},
+			{true, "", "", "liabilities", false, "2000", account_metadata{}},
+			{true, "", "liabilities", "current_liabilities", false, "2100", account_metadata{}},
+			{true, "", "current_liabilities", "tax", false, "2110", account_metadata{}},
+			{true, "", "", "equity", false, "3000", account_metadata{}},
+			{true, "", "equity", "retained_earnings", false, "3100", account_metadata{}},
+			{true, "", "retained_earnings", "dividends", false, "3110", account_metadata{}},
+			{true, "", "retained_earnings", "income_statement", false, "3120", account_metadata{}},
+			{true, "", "income_statement", "Revenues", false, "3121", account_metadata{}},
+			{true, "", "income_statement", "ebitda", false, "3130", account_metadata{}},
+			{true, "", "ebitda", "sales", false, "3131", account_metadata{}},
+			{true, "", "sales", "service revenue", false, "31311", account_metadata{}},
+			{true, "", "sales", "revenue of book", false, "31312", account_metadata{}},
+			{false, "", "ebitda", "expair_expenses", false, "3132", account_metadata{}},
+			{false, "", "ebitda", "cost_of_goods_sold", false, "3133", account_metadata{}},
+			{false, "", "cost_of_goods_sold", "cost of book", false, "31331", account_metadata{}},
+			{false, "", "ebitda", "discounts", false, "3134", account_metadata{}},
+			{false, "", "discounts", "discount of book", false, "31341", account_metadata{}},
+			{false, "", "discounts", "invoice_discount", false, "31342", account_metadata{}},
+			{false, "", "discounts", "service_discount", false, "31343", account_metadata{}},
+			{false, "", "income_statement", "expenses", false, "3140", account_metadata{}},
+			{false, "", "expenses", "interest_expense", false, "3141", account_metadata{}},
+			{false, "", "expenses", "tax of book", false, "3142", account_metadata{}},
+			{false, "", "expenses", "tax of service revenue", false, "3143", account_metadata{}},
+			{false, "", "expenses", "invoice_tax", false, "3144", account_metadata{}}},
+		Invoice_discounts_list: [][2]decimal.Decimal{{decimal.NewFromInt(5), decimal.NewFromInt(-10)}},
+		auto_complete_entries: [][]account_method_value_price{{{"service revenue", "quantity_ratio", decimal.NewFromInt(0), decimal.NewFromInt(10)}, {"tax of service revenue", "value", decimal.NewFromInt(1), decimal.NewFromInt(1)}, {"tax", "value", decimal.NewFromInt(1), decimal.NewFromInt(1)}, {"service_discount", "value", decimal.NewFromInt(1), decimal.NewFromInt(1)}},
+			{{"book", "quantity_ratio", decimal.NewFromInt(-1), decimal.NewFromInt(0)}, {"revenue of book", "quantity_ratio", decimal.NewFromInt(1), decimal.NewFromInt(10)}, {"cost of book", "copy_abs", decimal.NewFromInt(0), decimal.NewFromInt(0)}, {"tax of book", "value", decimal.NewFromInt(1), decimal.NewFromInt(1)}, {"tax", "value", decimal.NewFromInt(1), decimal.NewFromInt(1)}, {"discount of book", "value", decimal.NewFromInt(1), decimal.NewFromInt(1)}}},
+		transaction_templates: []transaction_template{
+			{"render service", []template_parameter{{"hours", "quantity"}, {"rate", "value"}, {"tax_rate", "percent"}}, []template_line{
+				{"service revenue", "", "formula", "hours*rate", "", "hours"},
+				{"tax", "", "percent_of_line", "tax_rate", "line1", ""},
+				{"cash", "", "formula", "line1+line2", "", ""}}},
+			{"sell book", []template_parameter{{"quantity", "quantity"}, {"price", "value"}, {"discount", "percent"}}, []template_line{
+				{"revenue of book", "", "formula", "quantity*price", "", "quantity"},
+				{"discount of book", "", "percent_of_line", "discount", "line1", ""},
+				{"cash", "", "formula", "line1-line2", "", ""}}},
+			{"deliver book", []template_parameter{{"quantity", "quantity"}}, []template_line{
+				{"book", "", "fixed", "0", "", "-quantity"},
+				{"cost of book", "", "formula", "-line1", "", ""}}}},
 	}
 	i.initialize()
 
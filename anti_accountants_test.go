@@ -0,0 +1,315 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var test_day = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// test_company returns the company of the tests on the driver, the sqlite database is a file in the temp dir of the test
+func test_company(t *testing.T, driver, data_source_name string) Financial_accounting {
+	t.Helper()
+	if driver == "sqlite3" && data_source_name == "" {
+		data_source_name = t.TempDir() + "/acc.sqlite"
+	}
+	return Financial_accounting{
+		date_layout:               []string{"2006-01-02 15:04:05.999999999 -0700 MST"},
+		DriverName:                driver,
+		DataSourceName:            data_source_name,
+		Database_name:             "acc",
+		assets:                    "assets",
+		current_assets:            "current_assets",
+		cash_and_cash_equivalents: "cash_and_cash_equivalents",
+		short_term_investments:    "short_term_investments",
+		receivables:               "receivables",
+		inventory:                 "inventory",
+		liabilities:               "liabilities",
+		current_liabilities:       "current_liabilities",
+		equity:                    "equity",
+		retained_earnings:         "retained_earnings",
+		dividends:                 "dividends",
+		income_statement:          "income_statement",
+		ebitda:                    "ebitda",
+		sales:                     "sales",
+		cost_of_goods_sold:        "cost_of_goods_sold",
+		discounts:                 "discounts",
+		invoice_discount:          "invoice_discount",
+		interest_expense:          "interest_expense",
+		accounts: []account{
+			{false, "", "", "assets", false, "", account_metadata{}},
+			{false, "", "assets", "current_assets", false, "", account_metadata{}},
+			{false, "", "current_assets", "cash_and_cash_equivalents", false, "", account_metadata{}},
+			{false, "", "cash_and_cash_equivalents", "cash", false, "", account_metadata{}},
+			{false, "", "current_assets", "short_term_investments", false, "", account_metadata{}},
+			{false, "", "current_assets", "receivables", false, "", account_metadata{}},
+			{false, "", "current_assets", "inventory", false, "", account_metadata{}},
+			{false, "wma", "inventory", "book", false, "", account_metadata{}},
+			{false, "fifo", "inventory", "panadol", false, "", account_metadata{}},
+			{false, "lifo", "inventory", "aspirin", false, "", account_metadata{}},
+			{false, "", "assets", "prepaid rent", false, "", account_metadata{}},
+			{true, "", "", "liabilities", false, "", account_metadata{}},
+			{true, "", "liabilities", "current_liabilities", false, "", account_metadata{}},
+			{true, "", "current_liabilities", "tax", false, "", account_metadata{}},
+			{true, "", "", "equity", false, "", account_metadata{}},
+			{true, "", "equity", "capital", false, "", account_metadata{}},
+			{true, "", "equity", "retained_earnings", false, "", account_metadata{}},
+			{false, "", "retained_earnings", "dividends", false, "", account_metadata{}},
+			{true, "", "retained_earnings", "income_statement", false, "", account_metadata{}},
+			{true, "", "income_statement", "ebitda", false, "", account_metadata{}},
+			{true, "", "ebitda", "sales", false, "", account_metadata{}},
+			{true, "", "sales", "revenue of book", false, "", account_metadata{}},
+			{false, "", "ebitda", "cost_of_goods_sold", false, "", account_metadata{}},
+			{false, "", "cost_of_goods_sold", "cost of book", false, "", account_metadata{}},
+			{false, "", "ebitda", "discounts", false, "", account_metadata{}},
+			{false, "", "discounts", "invoice_discount", false, "", account_metadata{}},
+			{false, "", "income_statement", "expenses", false, "", account_metadata{}},
+			{false, "", "expenses", "rent", false, "", account_metadata{}},
+			{false, "", "expenses", "interest_expense", false, "", account_metadata{}},
+		},
+	}
+}
+
+func check(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func number(f float64) decimal.Decimal {
+	return decimal.NewFromFloat(f)
+}
+
+func line(account string, value, quantity float64) Account_value_quantity_barcode {
+	return Account_value_quantity_barcode{account, number(value), number(quantity), ""}
+}
+
+func post(t *testing.T, s Financial_accounting, date time.Time, lines ...Account_value_quantity_barcode) []journal_tag {
+	t.Helper()
+	entry, err := s.journal_entry(lines, true, false, date, time.Time{}, "", "", "", "clerk", nil)
+	check(t, err)
+	return entry
+}
+
+func balance_of(t *testing.T, s Financial_accounting, account string) decimal.Decimal {
+	t.Helper()
+	balance, err := s.db.account_balance(account, test_day.AddDate(10, 0, 0))
+	check(t, err)
+	return balance
+}
+
+func expect_balance(t *testing.T, s Financial_accounting, account string, value float64) {
+	t.Helper()
+	if balance := balance_of(t, s, account); !balance.Equal(number(value)) {
+		t.Errorf("the balance of %s is %s not %v", account, balance, value)
+	}
+}
+
+// ledger_scenario posts, previews and consumes the inventory by fifo, lifo and wma then reads the statements,
+// it runs on every storage so they give the same results
+func ledger_scenario(t *testing.T, s Financial_accounting) {
+	s.initialize()
+	post(t, s, test_day, line("cash", 1000, 1000), line("capital", 1000, 1000))
+	post(t, s, test_day.AddDate(0, 0, 1), line("panadol", 100, 10), line("cash", -100, -100))
+	post(t, s, test_day.AddDate(0, 0, 2), line("panadol", 120, 10), line("cash", -120, -120))
+	post(t, s, test_day.AddDate(0, 0, 1), line("aspirin", 100, 10), line("cash", -100, -100))
+	post(t, s, test_day.AddDate(0, 0, 2), line("aspirin", 120, 10), line("cash", -120, -120))
+	post(t, s, test_day.AddDate(0, 0, 1), line("book", 50, 10), line("cash", -50, -50))
+	post(t, s, test_day.AddDate(0, 0, 2), line("book", 70, 10), line("cash", -70, -70))
+
+	_, err := s.journal_entry([]Account_value_quantity_barcode{line("panadol", -1, -15), line("cost of book", 160, 160)}, false, false, test_day.AddDate(0, 0, 3), time.Time{}, "", "", "", "clerk", nil)
+	check(t, err)
+	expect_balance(t, s, "panadol", 220)
+
+	// the value of the inventory that goes out is its cost so the lines are balanced by cost of book
+	sell := func(account string, quantity, cost float64) {
+		t.Helper()
+		post(t, s, test_day.AddDate(0, 0, 3), line(account, -1, -quantity), line("cost of book", cost, cost))
+	}
+	sell("panadol", 15, 160)
+	sell("aspirin", 15, 170)
+	sell("book", 5, 30)
+	expect_balance(t, s, "panadol", 60)
+	expect_balance(t, s, "aspirin", 50)
+	expect_balance(t, s, "book", 90)
+	expect_balance(t, s, "cash", 440)
+
+	_, err = s.journal_entry([]Account_value_quantity_barcode{line("panadol", -1, -6), line("cost of book", 1, 1)}, true, false, test_day.AddDate(0, 0, 3), time.Time{}, "", "", "", "clerk", nil)
+	if _, ok := err.(ErrInsufficientInventory); !ok {
+		t.Errorf("selling more than the inventory should be ErrInsufficientInventory not %v", err)
+	}
+
+	statements, _, _, err := s.financial_statements(test_day.AddDate(0, 0, -1), test_day.AddDate(0, 1, 0), 1, nil, false)
+	check(t, err)
+	if cash := statements[0][s.cash_and_cash_equivalents]["cash"]["all"]["value"]["ending_balance"]; !cash.Equal(number(440)) {
+		t.Errorf("the ending balance of cash in the statement is %s not 440", cash)
+	}
+}
+
+// every period of the adjusted entry is reversed in its own entry so the journal still starts
+func TestReverseAdjustedEntry(t *testing.T) {
+	s := test_company(t, "sqlite3", "")
+	s.initialize()
+	post(t, s, test_day, line("cash", 1000, 1000), line("capital", 1000, 1000))
+	entry, err := s.journal_entry([]Account_value_quantity_barcode{line("rent", 70, 70), line("cash", -70, -70)}, true, false, test_day, test_day.AddDate(0, 0, 7), "linear", "", "", "clerk", nil)
+	check(t, err)
+	check(t, s.reverse_entry(uint(entry[0].entry_number), "boss"))
+	expect_balance(t, s, "cash", 1000)
+	expect_balance(t, s, "rent", 0)
+	journal, err := s.db.journal()
+	check(t, err)
+	reversing := map[int]bool{}
+	for _, tag := range journal {
+		if tag.entry_number != entry[0].entry_number && tag.entry_number != 1 {
+			reversing[tag.entry_number] = true
+		}
+	}
+	if len(reversing) < 2 {
+		t.Errorf("the periods are reversed in %d entries", len(reversing))
+	}
+	s.initialize()
+}
+
+// the future entry is deleted with its header and what it did to the inventory
+func TestReverseFutureEntry(t *testing.T) {
+	for _, driver := range []string{"memory", "sqlite3"} {
+		s := test_company(t, driver, "")
+		s.initialize()
+		future := Now.AddDate(0, 0, 5)
+		post(t, s, test_day, line("cash", 1000, 1000), line("capital", 1000, 1000))
+		bought := post(t, s, future, line("panadol", 100, 10), line("cash", -100, -100))
+		check(t, s.reverse_entry(uint(bought[0].entry_number), "boss"))
+		if _, _, err := s.posted_entry(uint(bought[0].entry_number)); err == nil {
+			t.Errorf("%s: the header of the deleted entry is still there", driver)
+		}
+		layers, err := s.db.inventory_layers("panadol", "", "asc")
+		check(t, err)
+		if len(layers) != 0 {
+			t.Errorf("%s: the layer of the deleted entry is still there %v", driver, layers)
+		}
+
+		post(t, s, test_day, line("panadol", 100, 10), line("cash", -100, -100))
+		sold := post(t, s, future, line("panadol", -1, -4), line("cost of book", 40, 40))
+		check(t, s.reverse_entry(uint(sold[0].entry_number), "boss"))
+		layers, err = s.db.inventory_layers("panadol", "", "asc")
+		check(t, err)
+		var quantity decimal.Decimal
+		for _, layer := range layers {
+			quantity = quantity.Add(layer.quantity)
+		}
+		if !quantity.Equal(number(10)) {
+			t.Errorf("%s: the inventory of panadol is %s not 10 after the sale is deleted", driver, quantity)
+		}
+		expect_balance(t, s, "panadol", 100)
+	}
+}
+
+// the unknown accounts and the wrong arguments are returned as errors and don't panic
+func TestUnknownAccountErrors(t *testing.T) {
+	s := test_company(t, "memory", "")
+	s.initialize()
+	if _, err := s.is_credit("nothing"); err != (ErrUnknownAccount{"nothing"}) {
+		t.Errorf("is_credit of an unknown account should be ErrUnknownAccount not %v", err)
+	}
+	if _, err := s.entry_flows([]journal_tag{{account: "cash", value: number(1)}, {account: "nothing", value: number(1)}}); err == nil {
+		t.Error("entry_flows should return the error of the unknown account")
+	}
+	if _, err := s.select_journal(0, "", test_day, test_day.AddDate(0, 1, 0)); err == nil {
+		t.Error("select_journal without an entry_number and an account should return an error")
+	}
+	post(t, s, test_day, line("cash", 1000, 1000), line("capital", 1000, 1000))
+	journal, err := s.select_journal(0, "cash", test_day.AddDate(0, 0, -1), test_day.AddDate(0, 1, 0))
+	check(t, err)
+	if len(journal) != 1 {
+		t.Errorf("select_journal of cash returned %d lines not 1", len(journal))
+	}
+}
+
+// initialize stops on the config that check_config refuses
+func TestInitializeChecksConfig(t *testing.T) {
+	s := test_company(t, "memory", "")
+	s.transaction_templates = []transaction_template{{"sale", nil, []template_line{{"nothing", "", "fixed", "1", "", ""}, {"cash", "", "fixed", "-1", "", ""}}}}
+	defer func() {
+		if recover() == nil {
+			t.Error("initialize should stop on the template of an unknown account")
+		}
+	}()
+	s.initialize()
+}
+
+// the last slot of the schedule takes what the rounding left so the slots sum to the value of the entry,
+// when a day has more than one slot and when the schedule has the weekdays only
+func TestAdjustedScheduleRemainder(t *testing.T) {
+	s := test_company(t, "memory", "")
+	s.initialize()
+	post(t, s, test_day, line("cash", 1000, 1000), line("capital", 1000, 1000))
+	weekdays := []day_start_end{}
+	for _, day := range []string{"monday", "tuesday", "wednesday", "thursday", "friday"} {
+		weekdays = append(weekdays, day_start_end{day, 9, 0, 17, 0})
+	}
+	for _, schedule := range []struct {
+		days  []day_start_end
+		slots int
+	}{
+		{[]day_start_end{{"friday", 8, 0, 12, 0}, {"friday", 13, 0, 17, 0}}, 6},
+		{weekdays, 11},
+	} {
+		for _, method := range []string{"linear", "exponential", "logarithmic"} {
+			entry, err := s.journal_entry([]Account_value_quantity_barcode{line("rent", 100, 100), line("cash", -100, -100)}, false, false, test_day, test_day.AddDate(0, 0, 14), method, "", "", "clerk",
+				append([]day_start_end{}, schedule.days...))
+			check(t, err)
+			var slots int
+			var sum decimal.Decimal
+			for _, tag := range entry {
+				if tag.account != "rent" {
+					continue
+				}
+				if !tag.value.IsPositive() {
+					t.Errorf("%s: the slot of %s has the value %s", method, tag.date, tag.value)
+				}
+				slots++
+				sum = sum.Add(tag.value)
+			}
+			if slots != schedule.slots {
+				t.Errorf("%s: the schedule of %v has %d slots not %d", method, schedule.days, slots, schedule.slots)
+			}
+			if !sum.Equal(number(100)) {
+				t.Errorf("%s: the slots of %v sum to %s not 100", method, schedule.days, sum)
+			}
+		}
+	}
+}
+
+// every company has its own database, chart and inventory accounts so two of them are posted side by side
+func TestTwoCompanies(t *testing.T) {
+	for _, driver := range []string{"memory", "sqlite3"} {
+		first := test_company(t, driver, "")
+		second := test_company(t, driver, "")
+		second.Database_name = "acc2"
+		second.accounts[index_of_account(second.accounts, "panadol")].cost_flow_type = ""
+		first.initialize()
+		second.initialize()
+		post(t, first, test_day, line("cash", 1000, 1000), line("capital", 1000, 1000))
+		post(t, second, test_day, line("cash", 500, 500), line("capital", 500, 500))
+		post(t, first, test_day, line("panadol", 100, 10), line("cash", -100, -100))
+		post(t, second, test_day, line("panadol", 100, 10), line("cash", -100, -100))
+		expect_balance(t, first, "cash", 900)
+		expect_balance(t, second, "cash", 400)
+		if !IS_IN("panadol", first.inventory_accounts) || IS_IN("panadol", second.inventory_accounts) {
+			t.Errorf("%s: the inventory accounts are %v and %v", driver, first.inventory_accounts, second.inventory_accounts)
+		}
+		layers, err := second.db.inventory_layers("panadol", "", "asc")
+		check(t, err)
+		if len(layers) != 0 {
+			t.Errorf("%s: panadol is not an inventory account of the second company and it has the layers %v", driver, layers)
+		}
+		layers, err = first.db.inventory_layers("panadol", "", "asc")
+		check(t, err)
+		if len(layers) != 1 {
+			t.Errorf("%s: the first company has the layers %v of panadol", driver, layers)
+		}
+	}
+}
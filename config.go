@@ -0,0 +1,604 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+)
+
+// config_file is the config of the company as it is written in the yaml, json and csv files,
+// load_config and save_config choose the format from the extension of the file.
+// the csv file has one row for every field, the first column is the kind of the row:
+//
+//	setting,driver_name,sqlite3
+//	setting,date_layout,2006-01-02 15:04:05.999999999 -0700 MST
+//	special_account,assets,assets
+//	special_account,exchange_difference,fx gain or loss (optional, it is written only if it is not empty)
+//	account,name,father,is_credit,cost_flow_type,inactive,code,contra,cash_flow_category,currency,tax_category,allow_negative
+//	invoice_discount,total,discount
+//	auto_complete,group,account,method,value_or_percent,price
+//	template_parameter,template,name,kind
+//	template_line,template,account,barcode,method,amount,of,quantity
+//	approval_rule,account,min_value,approvers separated by ;
+//	period_manager,employee_name
+type config_file struct {
+	Date_layout               []string                  `yaml:"date_layout" json:"date_layout"`
+	Driver_name               string                    `yaml:"driver_name" json:"driver_name"`
+	Data_source_name          string                    `yaml:"data_source_name" json:"data_source_name"`
+	Database_name             string                    `yaml:"database_name" json:"database_name"`
+	Value_places              int32                     `yaml:"value_places,omitempty" json:"value_places,omitempty"`
+	Quantity_places           int32                     `yaml:"quantity_places,omitempty" json:"quantity_places,omitempty"`
+	Assets                    string                    `yaml:"assets" json:"assets"`
+	Current_assets            string                    `yaml:"current_assets" json:"current_assets"`
+	Cash_and_cash_equivalents string                    `yaml:"cash_and_cash_equivalents" json:"cash_and_cash_equivalents"`
+	Short_term_investments    string                    `yaml:"short_term_investments" json:"short_term_investments"`
+	Receivables               string                    `yaml:"receivables" json:"receivables"`
+	Inventory                 string                    `yaml:"inventory" json:"inventory"`
+	Liabilities               string                    `yaml:"liabilities" json:"liabilities"`
+	Current_liabilities       string                    `yaml:"current_liabilities" json:"current_liabilities"`
+	Equity                    string                    `yaml:"equity" json:"equity"`
+	Retained_earnings         string                    `yaml:"retained_earnings" json:"retained_earnings"`
+	Dividends                 string                    `yaml:"dividends" json:"dividends"`
+	Income_statement          string                    `yaml:"income_statement" json:"income_statement"`
+	Ebitda                    string                    `yaml:"ebitda" json:"ebitda"`
+	Sales                     string                    `yaml:"sales" json:"sales"`
+	Cost_of_goods_sold        string                    `yaml:"cost_of_goods_sold" json:"cost_of_goods_sold"`
+	Discounts                 string                    `yaml:"discounts" json:"discounts"`
+	Invoice_discount          string                    `yaml:"invoice_discount" json:"invoice_discount"`
+	Interest_expense          string                    `yaml:"interest_expense" json:"interest_expense"`
+	Exchange_difference       string                    `yaml:"exchange_difference,omitempty" json:"exchange_difference,omitempty"`
+	Accounts                  []config_account          `yaml:"accounts" json:"accounts"`
+	Invoice_discounts_list    []config_invoice_discount `yaml:"invoice_discounts_list" json:"invoice_discounts_list"`
+	Auto_complete_entries     [][]config_auto_complete  `yaml:"auto_complete_entries" json:"auto_complete_entries"`
+	Transaction_templates     []config_template         `yaml:"transaction_templates,omitempty" json:"transaction_templates,omitempty"`
+	Approval_rules            []config_approval_rule    `yaml:"approval_rules,omitempty" json:"approval_rules,omitempty"`
+	Period_managers           []string                  `yaml:"period_managers,omitempty" json:"period_managers,omitempty"`
+}
+
+type config_account struct {
+	Name               string `yaml:"name" json:"name"`
+	Father             string `yaml:"father" json:"father"`
+	Is_credit          bool   `yaml:"is_credit" json:"is_credit"`
+	Cost_flow_type     string `yaml:"cost_flow_type,omitempty" json:"cost_flow_type,omitempty"`
+	Inactive           bool   `yaml:"inactive,omitempty" json:"inactive,omitempty"`
+	Code               string `yaml:"code,omitempty" json:"code,omitempty"`
+	Contra             bool   `yaml:"contra,omitempty" json:"contra,omitempty"`
+	Cash_flow_category string `yaml:"cash_flow_category,omitempty" json:"cash_flow_category,omitempty"`
+	Currency           string `yaml:"currency,omitempty" json:"currency,omitempty"`
+	Tax_category       string `yaml:"tax_category,omitempty" json:"tax_category,omitempty"`
+	Allow_negative     bool   `yaml:"allow_negative,omitempty" json:"allow_negative,omitempty"`
+}
+
+// the discount is used when the total of the invoice is total or more
+type config_invoice_discount struct {
+	Total    decimal.Decimal `yaml:"total" json:"total"`
+	Discount decimal.Decimal `yaml:"discount" json:"discount"`
+}
+
+type config_auto_complete struct {
+	Account          string          `yaml:"account" json:"account"`
+	Method           string          `yaml:"method" json:"method"`
+	Value_or_percent decimal.Decimal `yaml:"value_or_percent" json:"value_or_percent"`
+	Price            decimal.Decimal `yaml:"price" json:"price"`
+}
+
+type config_template struct {
+	Name       string                      `yaml:"name" json:"name"`
+	Parameters []config_template_parameter `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+	Lines      []config_template_line      `yaml:"lines" json:"lines"`
+}
+
+type config_template_parameter struct {
+	Name string `yaml:"name" json:"name"`
+	Kind string `yaml:"kind" json:"kind"`
+}
+
+type config_template_line struct {
+	Account  string `yaml:"account" json:"account"`
+	Barcode  string `yaml:"barcode,omitempty" json:"barcode,omitempty"`
+	Method   string `yaml:"method" json:"method"`
+	Amount   string `yaml:"amount" json:"amount"`
+	Of       string `yaml:"of,omitempty" json:"of,omitempty"`
+	Quantity string `yaml:"quantity,omitempty" json:"quantity,omitempty"`
+}
+
+type config_approval_rule struct {
+	Account   string          `yaml:"account,omitempty" json:"account,omitempty"`
+	Min_value decimal.Decimal `yaml:"min_value" json:"min_value"`
+	Approvers []string        `yaml:"approvers,omitempty" json:"approvers,omitempty"`
+}
+
+func load_config(path string) (Financial_accounting, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Financial_accounting{}, err
+	}
+	defer file.Close()
+	s, err := read_config(file, filepath.Ext(path))
+	if err != nil {
+		return Financial_accounting{}, fmt.Errorf("%s: %w", path, err)
+	}
+	s.config_path = path
+	return s, nil
+}
+
+func (s Financial_accounting) save_config(path string) error {
+	var buffer bytes.Buffer
+	err := s.write_config(&buffer, filepath.Ext(path))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, buffer.Bytes(), 0644)
+}
+
+// read_config reads the config in the format of the extension and checks it as initialize would without opening the database
+func read_config(r io.Reader, extension string) (Financial_accounting, error) {
+	var c config_file
+	var err error
+	switch strings.ToLower(extension) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(r)
+		decoder.KnownFields(true)
+		err = decoder.Decode(&c)
+	case ".json":
+		decoder := json.NewDecoder(r)
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&c)
+	case ".csv":
+		c, err = read_config_csv(r)
+	default:
+		return Financial_accounting{}, ErrInvalidConfig{fmt.Sprint("the format ", extension, " is not in [.yaml,.yml,.json,.csv]")}
+	}
+	if err != nil {
+		return Financial_accounting{}, ErrInvalidConfig{err.Error()}
+	}
+	s := c.financial_accounting()
+	return s, s.check_config()
+}
+
+func (s Financial_accounting) write_config(w io.Writer, extension string) error {
+	c := s.config_file()
+	switch strings.ToLower(extension) {
+	case ".yaml", ".yml":
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		err := encoder.Encode(c)
+		if err != nil {
+			return err
+		}
+		return encoder.Close()
+	case ".json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(c)
+	case ".csv":
+		return write_config_csv(w, c)
+	}
+	return ErrInvalidConfig{fmt.Sprint("the format ", extension, " is not in [.yaml,.yml,.json,.csv]")}
+}
+
+// check_config checks the config before it is used, the chart is checked like initialize does
+func (s Financial_accounting) check_config() error {
+	return first_error(s.config_violations())
+}
+
+// config_violations is check_config that does not stop at the first problem
+func (s Financial_accounting) config_violations() []violation {
+	var violations []violation
+	if s.DriverName == "" {
+		violations = append(violations, violation{severity_error, "", 0, "driver_name is empty"})
+	}
+	if s.value_places < 0 || s.quantity_places < 0 {
+		violations = append(violations, violation{severity_error, "", 0, "value_places and quantity_places can't be negative"})
+	}
+	for _, a := range s.accounts {
+		if a.name == "" {
+			violations = append(violations, violation{severity_error, "", 0, "the name of the account can't be empty"})
+		}
+	}
+	c := s.config_file()
+	for _, field := range c.special_accounts() {
+		switch {
+		case *field.value == "":
+			violations = append(violations, violation{severity_error, "", 0, fmt.Sprint(field.key, " is empty")})
+		case !s.is_account(*field.value):
+			violations = append(violations, violation{severity_error, *field.value, 0, fmt.Sprint(field.key, " is ", *field.value, " and it is not in the accounts")})
+		case s.is_inactive(*field.value):
+			violations = append(violations, violation{severity_warning, *field.value, 0, fmt.Sprint(field.key, " is ", *field.value, " and it is deactivated")})
+		}
+	}
+	for _, field := range c.optional_special_accounts() {
+		if *field.value != "" && !s.is_account(*field.value) {
+			violations = append(violations, violation{severity_error, *field.value, 0, fmt.Sprint(field.key, " is ", *field.value, " and it is not in the accounts")})
+		}
+	}
+	var tree_violations []violation
+	s.tree, tree_violations = build_account_tree(s.accounts)
+	violations = append(violations, tree_violations...)
+	_, chart_violations := s.chart_violations()
+	violations = append(violations, chart_violations...)
+	for index, i := range s.Invoice_discounts_list {
+		if index > 0 && !i[0].GreaterThan(s.Invoice_discounts_list[index-1][0]) {
+			violations = append(violations, violation{severity_error, s.invoice_discount, 0, fmt.Sprint("the totals of invoice_discounts_list should be in ascending order ", s.Invoice_discounts_list)})
+			break
+		}
+	}
+	for _, complement := range s.auto_complete_entries {
+		if len(complement) == 0 {
+			violations = append(violations, violation{severity_error, "", 0, "auto_complete_entries has an empty group"})
+		}
+		for index, i := range complement {
+			switch {
+			case !s.is_account(i.account):
+				violations = append(violations, violation{severity_error, i.account, 0, fmt.Sprint(i.account, " in auto_complete_entries is not in the accounts")})
+			case s.is_inactive(i.account):
+				violations = append(violations, violation{severity_warning, i.account, 0, fmt.Sprint(i.account, " in auto_complete_entries is deactivated so the entries that use it will fail")})
+			}
+			if index > 0 && !IS_IN(i.method, []string{"copy_abs", "copy", "quantity_ratio", "value"}) {
+				violations = append(violations, violation{severity_error, i.account, 0, fmt.Sprint(i.method, " in the method field for ", i.account, " dose not exist you just can use copy_abs or copy or quantity_ratio or value")})
+			}
+		}
+	}
+	violations = append(violations, s.template_violations()...)
+	return append(violations, s.approval_rule_violations()...)
+}
+
+func (c config_file) financial_accounting() Financial_accounting {
+	s := Financial_accounting{
+		date_layout:               c.Date_layout,
+		DriverName:                c.Driver_name,
+		DataSourceName:            c.Data_source_name,
+		Database_name:             c.Database_name,
+		value_places:              c.Value_places,
+		quantity_places:           c.Quantity_places,
+		assets:                    c.Assets,
+		current_assets:            c.Current_assets,
+		cash_and_cash_equivalents: c.Cash_and_cash_equivalents,
+		short_term_investments:    c.Short_term_investments,
+		receivables:               c.Receivables,
+		inventory:                 c.Inventory,
+		liabilities:               c.Liabilities,
+		current_liabilities:       c.Current_liabilities,
+		equity:                    c.Equity,
+		retained_earnings:         c.Retained_earnings,
+		dividends:                 c.Dividends,
+		income_statement:          c.Income_statement,
+		ebitda:                    c.Ebitda,
+		sales:                     c.Sales,
+		cost_of_goods_sold:        c.Cost_of_goods_sold,
+		discounts:                 c.Discounts,
+		invoice_discount:          c.Invoice_discount,
+		interest_expense:          c.Interest_expense,
+		exchange_difference:       c.Exchange_difference,
+		period_managers:           c.Period_managers,
+	}
+	for _, a := range c.Accounts {
+		s.accounts = append(s.accounts, account{a.Is_credit, a.Cost_flow_type, a.Father, a.Name, a.Inactive, a.Code,
+			account_metadata{a.Contra, a.Cash_flow_category, a.Currency, a.Tax_category, a.Allow_negative}})
+	}
+	for _, a := range c.Invoice_discounts_list {
+		s.Invoice_discounts_list = append(s.Invoice_discounts_list, [2]decimal.Decimal{a.Total, a.Discount})
+	}
+	for _, complement := range c.Auto_complete_entries {
+		var group []account_method_value_price
+		for _, a := range complement {
+			group = append(group, account_method_value_price{a.Account, a.Method, a.Value_or_percent, a.Price})
+		}
+		s.auto_complete_entries = append(s.auto_complete_entries, group)
+	}
+	for _, a := range c.Transaction_templates {
+		template := transaction_template{name: a.Name}
+		for _, i := range a.Parameters {
+			template.parameters = append(template.parameters, template_parameter{i.Name, i.Kind})
+		}
+		for _, i := range a.Lines {
+			template.lines = append(template.lines, template_line{i.Account, i.Barcode, i.Method, i.Amount, i.Of, i.Quantity})
+		}
+		s.transaction_templates = append(s.transaction_templates, template)
+	}
+	for _, a := range c.Approval_rules {
+		s.approval_rules = append(s.approval_rules, approval_rule{a.Account, a.Min_value, a.Approvers})
+	}
+	return s
+}
+
+func (s Financial_accounting) config_file() config_file {
+	c := config_file{
+		Date_layout:               s.date_layout,
+		Driver_name:               s.DriverName,
+		Data_source_name:          s.DataSourceName,
+		Database_name:             s.Database_name,
+		Value_places:              s.value_places,
+		Quantity_places:           s.quantity_places,
+		Assets:                    s.assets,
+		Current_assets:            s.current_assets,
+		Cash_and_cash_equivalents: s.cash_and_cash_equivalents,
+		Short_term_investments:    s.short_term_investments,
+		Receivables:               s.receivables,
+		Inventory:                 s.inventory,
+		Liabilities:               s.liabilities,
+		Current_liabilities:       s.current_liabilities,
+		Equity:                    s.equity,
+		Retained_earnings:         s.retained_earnings,
+		Dividends:                 s.dividends,
+		Income_statement:          s.income_statement,
+		Ebitda:                    s.ebitda,
+		Sales:                     s.sales,
+		Cost_of_goods_sold:        s.cost_of_goods_sold,
+		Discounts:                 s.discounts,
+		Invoice_discount:          s.invoice_discount,
+		Interest_expense:          s.interest_expense,
+		Exchange_difference:       s.exchange_difference,
+		Period_managers:           s.period_managers,
+	}
+	for _, a := range s.accounts {
+		c.Accounts = append(c.Accounts, config_account{a.name, a.father, a.is_credit, a.cost_flow_type, a.inactive, a.code,
+			a.contra, a.cash_flow_category, a.currency, a.tax_category, a.allow_negative})
+	}
+	for _, a := range s.Invoice_discounts_list {
+		c.Invoice_discounts_list = append(c.Invoice_discounts_list, config_invoice_discount{a[0], a[1]})
+	}
+	for _, complement := range s.auto_complete_entries {
+		var group []config_auto_complete
+		for _, a := range complement {
+			group = append(group, config_auto_complete{a.account, a.method, a.value_or_percent, a.price})
+		}
+		c.Auto_complete_entries = append(c.Auto_complete_entries, group)
+	}
+	for _, a := range s.transaction_templates {
+		template := config_template{Name: a.name}
+		for _, i := range a.parameters {
+			template.Parameters = append(template.Parameters, config_template_parameter{i.name, i.kind})
+		}
+		for _, i := range a.lines {
+			template.Lines = append(template.Lines, config_template_line{i.account, i.barcode, i.method, i.amount, i.of, i.quantity})
+		}
+		c.Transaction_templates = append(c.Transaction_templates, template)
+	}
+	for _, a := range s.approval_rules {
+		c.Approval_rules = append(c.Approval_rules, config_approval_rule{a.account, a.min_value, a.approvers})
+	}
+	return c
+}
+
+type config_field struct {
+	key   string
+	value *string
+}
+
+// special_accounts is in the order of the fields so the csv file is written in the same order every time
+func (c *config_file) special_accounts() []config_field {
+	return []config_field{
+		{"assets", &c.Assets},
+		{"current_assets", &c.Current_assets},
+		{"cash_and_cash_equivalents", &c.Cash_and_cash_equivalents},
+		{"short_term_investments", &c.Short_term_investments},
+		{"receivables", &c.Receivables},
+		{"inventory", &c.Inventory},
+		{"liabilities", &c.Liabilities},
+		{"current_liabilities", &c.Current_liabilities},
+		{"equity", &c.Equity},
+		{"retained_earnings", &c.Retained_earnings},
+		{"dividends", &c.Dividends},
+		{"income_statement", &c.Income_statement},
+		{"ebitda", &c.Ebitda},
+		{"sales", &c.Sales},
+		{"cost_of_goods_sold", &c.Cost_of_goods_sold},
+		{"discounts", &c.Discounts},
+		{"invoice_discount", &c.Invoice_discount},
+		{"interest_expense", &c.Interest_expense},
+	}
+}
+
+// optional_special_accounts are needed by some operations only so they can be empty, like exchange_difference for revalue_currencies
+func (c *config_file) optional_special_accounts() []config_field {
+	return []config_field{
+		{"exchange_difference", &c.Exchange_difference},
+	}
+}
+
+func (c *config_file) settings() []config_field {
+	return []config_field{
+		{"driver_name", &c.Driver_name},
+		{"data_source_name", &c.Data_source_name},
+		{"database_name", &c.Database_name},
+	}
+}
+
+func read_config_csv(r io.Reader) (config_file, error) {
+	var c config_file
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	auto_complete_groups := map[string]int{}
+	templates := map[string]int{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return c, nil
+		}
+		if err != nil {
+			return c, err
+		}
+		line, _ := reader.FieldPos(0)
+		err = c.read_csv_record(record, auto_complete_groups, templates)
+		if err != nil {
+			return c, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+}
+
+// the rows of one template can be anywhere in the file, templates has the index of every template that is read
+func (c *config_file) read_csv_record(record []string, auto_complete_groups, templates map[string]int) error {
+	fields := map[string]int{"setting": 3, "special_account": 3, "account": 12, "invoice_discount": 3, "auto_complete": 6, "template_parameter": 4, "template_line": 8, "approval_rule": 4, "period_manager": 2}
+	if fields[record[0]] == 0 {
+		return fmt.Errorf("%s is not in [setting,special_account,account,invoice_discount,auto_complete,template_parameter,template_line,approval_rule,period_manager]", record[0])
+	}
+	if len(record) != fields[record[0]] {
+		return fmt.Errorf("%s should have %d fields not %d", record[0], fields[record[0]], len(record))
+	}
+	switch record[0] {
+	case "setting":
+		switch record[1] {
+		case "date_layout":
+			c.Date_layout = append(c.Date_layout, record[2])
+			return nil
+		case "value_places", "quantity_places":
+			places, err := strconv.ParseInt(record[2], 10, 32)
+			if err != nil {
+				return err
+			}
+			if record[1] == "value_places" {
+				c.Value_places = int32(places)
+			} else {
+				c.Quantity_places = int32(places)
+			}
+			return nil
+		}
+		return set_config_field(c.settings(), record[1], record[2])
+	case "special_account":
+		return set_config_field(append(c.special_accounts(), c.optional_special_accounts()...), record[1], record[2])
+	case "account":
+		var flags [4]bool
+		for index, field := range []int{3, 5, 7, 11} {
+			if record[field] == "" {
+				continue
+			}
+			var err error
+			flags[index], err = strconv.ParseBool(record[field])
+			if err != nil {
+				return err
+			}
+		}
+		c.Accounts = append(c.Accounts, config_account{record[1], record[2], flags[0], record[4], flags[1], record[6], flags[2], record[8], record[9], record[10], flags[3]})
+	case "invoice_discount":
+		numbers, err := parse_decimals(record[1:])
+		if err != nil {
+			return err
+		}
+		c.Invoice_discounts_list = append(c.Invoice_discounts_list, config_invoice_discount{numbers[0], numbers[1]})
+	case "auto_complete":
+		numbers, err := parse_decimals(record[4:])
+		if err != nil {
+			return err
+		}
+		index, ok := auto_complete_groups[record[1]]
+		if !ok {
+			index = len(c.Auto_complete_entries)
+			auto_complete_groups[record[1]] = index
+			c.Auto_complete_entries = append(c.Auto_complete_entries, nil)
+		}
+		c.Auto_complete_entries[index] = append(c.Auto_complete_entries[index], config_auto_complete{record[2], record[3], numbers[0], numbers[1]})
+	case "template_parameter", "template_line":
+		index, ok := templates[record[1]]
+		if !ok {
+			index = len(c.Transaction_templates)
+			templates[record[1]] = index
+			c.Transaction_templates = append(c.Transaction_templates, config_template{Name: record[1]})
+		}
+		template := &c.Transaction_templates[index]
+		if record[0] == "template_parameter" {
+			template.Parameters = append(template.Parameters, config_template_parameter{record[2], record[3]})
+		} else {
+			template.Lines = append(template.Lines, config_template_line{record[2], record[3], record[4], record[5], record[6], record[7]})
+		}
+	case "approval_rule":
+		numbers, err := parse_decimals(record[2:3])
+		if err != nil {
+			return err
+		}
+		var approvers []string
+		if record[3] != "" {
+			approvers = strings.Split(record[3], ";")
+		}
+		c.Approval_rules = append(c.Approval_rules, config_approval_rule{record[1], numbers[0], approvers})
+	case "period_manager":
+		c.Period_managers = append(c.Period_managers, record[1])
+	}
+	return nil
+}
+
+func set_config_field(fields []config_field, key, value string) error {
+	for _, field := range fields {
+		if field.key == key {
+			*field.value = value
+			return nil
+		}
+	}
+	var keys []string
+	for _, field := range fields {
+		keys = append(keys, field.key)
+	}
+	return fmt.Errorf("%s is not in [%s]", key, strings.Join(keys, ","))
+}
+
+func parse_decimals(texts []string) ([]decimal.Decimal, error) {
+	var numbers []decimal.Decimal
+	for _, text := range texts {
+		number, err := decimal.NewFromString(text)
+		if err != nil {
+			return nil, err
+		}
+		numbers = append(numbers, number)
+	}
+	return numbers, nil
+}
+
+func write_config_csv(w io.Writer, c config_file) error {
+	writer := csv.NewWriter(w)
+	var records [][]string
+	for _, field := range c.settings() {
+		records = append(records, []string{"setting", field.key, *field.value})
+	}
+	for _, layout := range c.Date_layout {
+		records = append(records, []string{"setting", "date_layout", layout})
+	}
+	if c.Value_places != 0 || c.Quantity_places != 0 {
+		records = append(records, []string{"setting", "value_places", fmt.Sprint(c.Value_places)})
+		records = append(records, []string{"setting", "quantity_places", fmt.Sprint(c.Quantity_places)})
+	}
+	for _, field := range c.special_accounts() {
+		records = append(records, []string{"special_account", field.key, *field.value})
+	}
+	for _, field := range c.optional_special_accounts() {
+		if *field.value != "" {
+			records = append(records, []string{"special_account", field.key, *field.value})
+		}
+	}
+	for _, a := range c.Accounts {
+		records = append(records, []string{"account", a.Name, a.Father, strconv.FormatBool(a.Is_credit), a.Cost_flow_type, strconv.FormatBool(a.Inactive), a.Code,
+			strconv.FormatBool(a.Contra), a.Cash_flow_category, a.Currency, a.Tax_category, strconv.FormatBool(a.Allow_negative)})
+	}
+	for _, a := range c.Invoice_discounts_list {
+		records = append(records, []string{"invoice_discount", a.Total.String(), a.Discount.String()})
+	}
+	for index, complement := range c.Auto_complete_entries {
+		for _, a := range complement {
+			records = append(records, []string{"auto_complete", fmt.Sprint(index + 1), a.Account, a.Method, a.Value_or_percent.String(), a.Price.String()})
+		}
+	}
+	for _, template := range c.Transaction_templates {
+		for _, a := range template.Parameters {
+			records = append(records, []string{"template_parameter", template.Name, a.Name, a.Kind})
+		}
+		for _, a := range template.Lines {
+			records = append(records, []string{"template_line", template.Name, a.Account, a.Barcode, a.Method, a.Amount, a.Of, a.Quantity})
+		}
+	}
+	for _, a := range c.Approval_rules {
+		records = append(records, []string{"approval_rule", a.Account, a.Min_value.String(), strings.Join(a.Approvers, ";")})
+	}
+	for _, a := range c.Period_managers {
+		records = append(records, []string{"period_manager", a})
+	}
+	return writer.WriteAll(records)
+}
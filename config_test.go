@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// config_company is test_company with every part of the config filled and decimals that float can't keep
+func config_company(t *testing.T) Financial_accounting {
+	t.Helper()
+	s := test_company(t, "sqlite3", "")
+	s.value_places, s.quantity_places = 3, 8
+	s.Invoice_discounts_list = [][2]decimal.Decimal{{number(100), decimal.RequireFromString("0.1")}, {decimal.RequireFromString("1000.005"), decimal.RequireFromString("0.333333333333333333")}}
+	s.auto_complete_entries = [][]account_method_value_price{
+		{{"book", "quantity_ratio", number(0), decimal.RequireFromString("12.345678901234567890")}, {"revenue of book", "copy_abs", number(0), number(1)}},
+		{{"rent", "copy", number(1), number(1)}, {"tax", "value", decimal.RequireFromString("0.14"), number(1)}},
+	}
+	s.accounts[index_of_account(s.accounts, "cash")].code = "1100"
+	s.accounts[index_of_account(s.accounts, "capital")].code = "3100"
+	s.accounts[index_of_account(s.accounts, "tax")].tax_category = "vat"
+	s.accounts[index_of_account(s.accounts, "cash")].cash_flow_category = ""
+	s.accounts[index_of_account(s.accounts, "receivables")].cash_flow_category = "operating"
+	s.accounts[index_of_account(s.accounts, "prepaid rent")].allow_negative = true
+	s.accounts = append(s.accounts,
+		account{false, "", "cash_and_cash_equivalents", "usd cash", false, "1110", account_metadata{currency: "USD"}},
+		account{true, "", "assets", "accumulated depreciation", false, "", account_metadata{contra: true}},
+		account{true, "", "income_statement", "exchange difference", false, "", account_metadata{}},
+		account{false, "", "expenses", "old rent", true, "", account_metadata{}})
+	s.exchange_difference = "exchange difference"
+	s.transaction_templates = []transaction_template{{"sell book", []template_parameter{{"quantity", "quantity"}, {"price", "value"}, {"tax_rate", "percent"}},
+		[]template_line{
+			{"revenue of book", "", "formula", "quantity*price", "", ""},
+			{"tax", "", "percent_of_line", "tax_rate", "line1", ""},
+			{"cash", "", "formula", "line1+line2", "", ""},
+			{"book", "", "formula", "-1", "", "-quantity"},
+			{"cost of book", "", "formula", "-line4", "", ""},
+		}}}
+	s.approval_rules = []approval_rule{{"rent", decimal.RequireFromString("999.995"), []string{"boss", "auditor"}}, {"", number(100000), nil}}
+	s.period_managers = []string{"boss"}
+	check(t, s.check_config())
+	return s
+}
+
+// the config that is saved and loaded again is the same in every format, the decimals keep all their places
+func TestConfigRoundTrip(t *testing.T) {
+	s := config_company(t)
+	expected, err := json.Marshal(s.config_file())
+	check(t, err)
+	for _, extension := range []string{".yaml", ".json", ".csv"} {
+		path := t.TempDir() + "/company" + extension
+		check(t, s.save_config(path))
+		loaded, err := load_config(path)
+		check(t, err)
+		if loaded.config_path != path {
+			t.Errorf("%s: config_path is %s not %s", extension, loaded.config_path, path)
+		}
+		actual, err := json.Marshal(loaded.config_file())
+		check(t, err)
+		if !bytes.Equal(actual, expected) {
+			t.Errorf("%s: the loaded config is not the saved one\n%s\n%s", extension, actual, expected)
+		}
+		if discount := loaded.Invoice_discounts_list[1][1]; !discount.Equal(decimal.RequireFromString("0.333333333333333333")) {
+			t.Errorf("%s: the discount is %s", extension, discount)
+		}
+		if price := loaded.auto_complete_entries[0][0].price; !price.Equal(decimal.RequireFromString("12.34567890123456789")) {
+			t.Errorf("%s: the price of the auto complete is %s", extension, price)
+		}
+		saved, err := os.ReadFile(path)
+		check(t, err)
+		check(t, loaded.save_config(path))
+		saved_again, err := os.ReadFile(path)
+		check(t, err)
+		if !bytes.Equal(saved, saved_again) {
+			t.Errorf("%s: the loaded config is saved differently\n%s\n%s", extension, saved, saved_again)
+		}
+	}
+}
+
+// the decimals are strings in the yaml file so yaml.v3 doesn't read them as float
+func TestConfigYamlDecimals(t *testing.T) {
+	var config bytes.Buffer
+	check(t, config_company(t).write_config(&config, ".yaml"))
+	if !strings.Contains(config.String(), "0.333333333333333333") {
+		t.Errorf("the yaml has not the discount with all its places\n%s", config.String())
+	}
+	loaded, err := read_config(strings.NewReader(strings.Replace(config.String(), `"0.333333333333333333"`, "0.333333333333333333", 1)), ".yaml")
+	check(t, err)
+	if discount := loaded.Invoice_discounts_list[1][1]; !discount.Equal(decimal.RequireFromString("0.333333333333333333")) {
+		t.Errorf("the discount that is not quoted in the yaml is read as %s", discount)
+	}
+}
+
+// the account rows of the csv file should have all the fields of the account
+func TestConfigCsvAccountFields(t *testing.T) {
+	var config strings.Builder
+	check(t, test_company(t, "memory", "").write_config(&config, ".csv"))
+	_, err := read_config(strings.NewReader(config.String()), ".csv")
+	check(t, err)
+	old := strings.Replace(config.String(), "account,cash,cash_and_cash_equivalents,false,,false,,false,,,,false", "account,cash,cash_and_cash_equivalents,false,,false", 1)
+	if old == config.String() {
+		t.Fatalf("the csv has no row of cash\n%s", config.String())
+	}
+	if _, err := read_config(strings.NewReader(old), ".csv"); err == nil || !strings.Contains(err.Error(), "account should have 12 fields not 6") {
+		t.Errorf("the account row of 6 fields should be refused not %v", err)
+	}
+}
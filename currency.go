@@ -0,0 +1,355 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// the values of the journal are in the currency of the company, the lines that are entered in another currency keep
+// their currency, the value in it and the rate so the accounts that have a currency can be revalued by the rates table.
+// the accounts that have a currency take their entries by foreign_entry only
+
+// foreign_line is a line of foreign_entry, the value is in currency and it is changed to the currency of the company by rate
+type foreign_line struct {
+	Account                 string
+	currency                string // empty is the currency of the company and then the rate is 1
+	foreign_value, quantity decimal.Decimal
+	barcode                 string
+	rate                    decimal.Decimal // the zero rate is the rate of the date in the rates table
+}
+
+type exchange_rate struct {
+	currency string
+	date     time.Time // the rate is used from date until the next rate of the currency
+	rate     decimal.Decimal
+}
+
+// foreign_entry posts the lines like journal_entry without auto completion and adjusting, the entry should balance
+// in the currency of the company after the values are changed by the rates and before they are rounded, the difference
+// that the rounding makes goes to exchange_difference. the zero quantity is the value
+func (s Financial_accounting) foreign_entry(lines []foreign_line, insert bool, date time.Time, description, name, employee_name string) ([]journal_tag, error) {
+	var all_array_to_insert []journal_tag
+	err := s.db.transaction(func(tx storage) error {
+		err := tx.lock_ledger()
+		if err != nil {
+			return err
+		}
+		var resolved []foreign_line
+		for _, line := range lines {
+			if line.foreign_value.IsZero() {
+				continue
+			}
+			entry := []Account_value_quantity_barcode{{Account: line.Account, barcode: line.barcode}}
+			s.account_of_code(entry)
+			err = find_barcode(tx, entry)
+			if err != nil {
+				return err
+			}
+			line.Account, line.barcode = entry[0].Account, entry[0].barcode
+			err = s.check_known_accounts(entry)
+			if err != nil {
+				return err
+			}
+			if currency := s.account_currency(line.Account); currency != "" && currency != line.currency {
+				return ErrInvalidEntry{nil, fmt.Sprint(line.Account, " is in ", currency, " and the line is in [", line.currency, "]")}
+			}
+			switch {
+			case line.currency == "":
+				line.rate = decimal.NewFromInt(1)
+			case line.rate.IsNegative():
+				return ErrInvalidEntry{nil, fmt.Sprint("the rate of ", line.currency, " for ", line.Account, " can't be negative")}
+			case line.rate.IsZero():
+				line.rate, err = s.exchange_rate_of(tx, line.currency, date)
+				if err != nil {
+					return err
+				}
+			}
+			if line.quantity.IsZero() {
+				line.quantity = line.foreign_value.Mul(line.rate)
+			}
+			resolved = append(resolved, line)
+		}
+		var array_of_entry []Account_value_quantity_barcode
+		var foreign []foreign_line
+		var exact_difference decimal.Decimal
+		for _, line := range group_foreign_lines(resolved) {
+			value := line.foreign_value.Mul(line.rate)
+			entry := Account_value_quantity_barcode{line.Account, s.round_value(value), s.round_quantity(line.quantity), line.barcode}
+			// the value of the inventory that goes out is its cost so it is in the currency of the company
+			costs, err := s.cost_flow(tx, entry.Account, entry.quantity, entry.barcode, false)
+			if err != nil {
+				return err
+			}
+			if !costs.IsZero() {
+				value = costs.Neg()
+				entry.value = value
+				line.currency, line.foreign_value, line.rate = "", value, decimal.NewFromInt(1)
+			}
+			is_credit, err := s.is_credit(entry.Account)
+			if err != nil {
+				return err
+			}
+			if is_credit {
+				exact_difference = exact_difference.Sub(value)
+			} else {
+				exact_difference = exact_difference.Add(value)
+			}
+			if entry.value.IsZero() || entry.quantity.IsZero() {
+				continue
+			}
+			array_of_entry = append(array_of_entry, entry)
+			foreign = append(foreign, line)
+		}
+		if !s.round_value(exact_difference).IsZero() {
+			return ErrUnbalancedEntry{exact_difference, array_of_entry}
+		}
+		array_of_entry, foreign, err = s.post_rounding_difference(array_of_entry, foreign)
+		if err != nil {
+			return err
+		}
+		err = s.can_the_account_be_negative(tx, array_of_entry)
+		if err != nil {
+			return err
+		}
+		_, _, err = s.check_debit_equal_credit(array_of_entry, false)
+		if err != nil {
+			return err
+		}
+		if insert {
+			err = s.check_approval_rules(array_of_entry)
+			if err != nil {
+				return err
+			}
+		}
+		all_array_to_insert, err = insert_to_journal_tag(array_of_entry, date, time.Time{}, description, name, employee_name)
+		if err != nil {
+			return err
+		}
+		for index, line := range foreign {
+			if line.currency != "" {
+				all_array_to_insert[index].currency, all_array_to_insert[index].foreign_value, all_array_to_insert[index].rate = line.currency, line.foreign_value, line.rate
+			}
+		}
+		err = s.check_periods(tx, all_array_to_insert, employee_name)
+		if err != nil {
+			return err
+		}
+		return s.insert_to_database(tx, all_array_to_insert, insert, insert, insert)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return all_array_to_insert, nil
+}
+
+// group_foreign_lines groups the lines by group_by_account_and_barcode in every currency and rate so every line keeps
+// one rate, the foreign values are summed in the values while they are grouped
+func group_foreign_lines(lines []foreign_line) []foreign_line {
+	var keys []string
+	groups := map[string][]Account_value_quantity_barcode{}
+	rates := map[string]foreign_line{}
+	for _, line := range lines {
+		key := line.currency + " " + line.rate.String()
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+			rates[key] = line
+		}
+		groups[key] = append(groups[key], Account_value_quantity_barcode{line.Account, line.foreign_value, line.quantity, line.barcode})
+	}
+	var grouped []foreign_line
+	for _, key := range keys {
+		for _, entry := range remove_zero_values(group_by_account_and_barcode(groups[key])) {
+			grouped = append(grouped, foreign_line{entry.Account, rates[key].currency, entry.value, entry.quantity, entry.barcode, rates[key].rate})
+		}
+	}
+	return grouped
+}
+
+// post_rounding_difference adds the difference that the rounding made to the line of exchange_difference in the currency
+// of the company or to a new line, the entry is unbalanced if exchange_difference is empty
+func (s Financial_accounting) post_rounding_difference(array_of_entry []Account_value_quantity_barcode, foreign []foreign_line) ([]Account_value_quantity_barcode, []foreign_line, error) {
+	var difference decimal.Decimal
+	for _, entry := range array_of_entry {
+		is_credit, err := s.is_credit(entry.Account)
+		if err != nil {
+			return nil, nil, err
+		}
+		if is_credit {
+			difference = difference.Sub(entry.value)
+		} else {
+			difference = difference.Add(entry.value)
+		}
+	}
+	if difference.IsZero() {
+		return array_of_entry, foreign, nil
+	}
+	if !s.is_account(s.exchange_difference) {
+		return nil, nil, ErrUnbalancedEntry{difference, array_of_entry}
+	}
+	is_credit, err := s.is_credit(s.exchange_difference)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !is_credit {
+		difference = difference.Neg()
+	}
+	var lines []Account_value_quantity_barcode
+	var lines_foreign []foreign_line
+	added := false
+	for index, entry := range array_of_entry {
+		line := foreign[index]
+		if !added && entry.Account == s.exchange_difference && entry.barcode == "" && line.currency == "" {
+			entry.value, entry.quantity = entry.value.Add(difference), entry.quantity.Add(difference)
+			line.foreign_value, line.quantity = entry.value, entry.quantity
+			added = true
+			if entry.value.IsZero() || entry.quantity.IsZero() {
+				continue
+			}
+		}
+		lines = append(lines, entry)
+		lines_foreign = append(lines_foreign, line)
+	}
+	if !added {
+		lines = append(lines, Account_value_quantity_barcode{s.exchange_difference, difference, difference, ""})
+		lines_foreign = append(lines_foreign, foreign_line{s.exchange_difference, "", difference, difference, "", decimal.NewFromInt(1)})
+	}
+	return lines, lines_foreign, nil
+}
+
+// check_company_currency is used by journal_entry because its lines are in the currency of the company
+func (s Financial_accounting) check_company_currency(array_of_entry []Account_value_quantity_barcode) error {
+	for _, entry := range array_of_entry {
+		if currency := s.account_currency(entry.Account); currency != "" {
+			return ErrInvalidEntry{[]Account_value_quantity_barcode{entry}, fmt.Sprint(entry.Account, " is in ", currency, " so it is entered by foreign_entry")}
+		}
+	}
+	return nil
+}
+
+func (s Financial_accounting) account_currency(name string) string {
+	index := index_of_account(s.accounts, name)
+	if index == -1 {
+		return ""
+	}
+	return s.accounts[index].currency
+}
+
+// set_exchange_rate saves the rate of one unit of currency in the currency of the company from date, the rate that
+// is on the same date is replaced
+func (s Financial_accounting) set_exchange_rate(currency string, date time.Time, rate decimal.Decimal) error {
+	if !is_currency_code(currency) {
+		return ErrInvalidEntry{nil, fmt.Sprint("the currency ", currency, " should be three capital letters like USD")}
+	}
+	if !rate.IsPositive() {
+		return ErrInvalidEntry{nil, fmt.Sprint("the rate of ", currency, " should be more than 0 not ", rate)}
+	}
+	return s.db.transaction(func(tx storage) error {
+		err := tx.delete_exchange_rate(currency, date)
+		if err != nil {
+			return err
+		}
+		return tx.insert_exchange_rate(exchange_rate{currency, date, rate})
+	})
+}
+
+func (s Financial_accounting) exchange_rates(currency string) ([]exchange_rate, error) {
+	return s.db.exchange_rates(currency)
+}
+
+// exchange_rate_of returns the last rate of the currency on or before date
+func (s Financial_accounting) exchange_rate_of(tx storage, currency string, date time.Time) (decimal.Decimal, error) {
+	rates, err := tx.exchange_rates(currency)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	var rate decimal.Decimal
+	for _, r := range rates {
+		if r.date.After(date) {
+			break
+		}
+		rate = r.rate
+	}
+	if rate.IsZero() {
+		return decimal.Zero, ErrUnknownRate{currency, date}
+	}
+	return rate, nil
+}
+
+// revalue_currencies revalues the balances before date like account_balance and the snapshots of date, every asset and
+// liability that has a currency takes an entry that makes its balance equal its balance in the currency by the last rate
+// before date and the difference goes to exchange_difference. the entry is on the last microsecond before date so it is in
+// the balance before date and running it again on the same date posts nothing. the inventory is not revalued because it is
+// not monetary. the lines are checked by can_the_account_be_negative like journal_entry, exchange_difference takes the
+// gains and the losses so it should allow_negative if it is not a credit account of equity
+func (s Financial_accounting) revalue_currencies(date time.Time, employee_name string) ([]journal_tag, error) {
+	// datetime(6) of mysql and timestamp of postgres keep the microseconds
+	entry_date := date.Add(-time.Microsecond)
+	if !s.is_account(s.exchange_difference) {
+		return nil, ErrInvalidConfig{fmt.Sprint("exchange_difference is [", s.exchange_difference, "] and it is not in the accounts")}
+	}
+	var all_array_to_insert []journal_tag
+	err := s.db.transaction(func(tx storage) error {
+		err := tx.lock_ledger()
+		if err != nil {
+			return err
+		}
+		for _, a := range s.accounts {
+			if a.currency == "" || IS_IN(a.name, s.inventory_accounts) || (!s.is_father(s.assets, a.name) && !s.is_father(s.liabilities, a.name)) {
+				continue
+			}
+			journal, err := tx.journal_of_account(a.name)
+			if err != nil {
+				return err
+			}
+			var value, foreign_value decimal.Decimal
+			for _, tag := range journal {
+				if tag.date.Before(date) {
+					value, foreign_value = value.Add(tag.value), foreign_value.Add(tag.foreign_value)
+				}
+			}
+			rate, err := s.exchange_rate_of(tx, a.currency, entry_date)
+			if err != nil {
+				return err
+			}
+			difference := s.round_value(foreign_value.Mul(rate)).Sub(value)
+			if difference.IsZero() {
+				continue
+			}
+			// the difference is on the other side of the account if they are on the same side
+			exchange_difference := difference
+			same_side, err := s.same_side(a.name, s.exchange_difference)
+			if err != nil {
+				return err
+			}
+			if same_side {
+				exchange_difference = difference.Neg()
+			}
+			lines := []Account_value_quantity_barcode{{a.name, difference, difference, ""}, {s.exchange_difference, exchange_difference, exchange_difference, ""}}
+			err = s.can_the_account_be_negative(tx, lines)
+			if err != nil {
+				return err
+			}
+			entry, err := insert_to_journal_tag(lines, entry_date, time.Time{}, fmt.Sprint("(revaluation of ", a.name, " by the rate ", rate, " of ", a.currency, ")"), "", employee_name)
+			if err != nil {
+				return err
+			}
+			entry[0].currency, entry[0].rate = a.currency, rate
+			err = s.check_periods(tx, entry, employee_name)
+			if err != nil {
+				return err
+			}
+			err = s.insert_to_database(tx, entry, true, true, true)
+			if err != nil {
+				return err
+			}
+			all_array_to_insert = append(all_array_to_insert, entry...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return all_array_to_insert, nil
+}
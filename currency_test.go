@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+)
+
+// currency_company is test_company with a cash account in USD and exchange difference under the income statement
+func currency_company(t *testing.T, driver string) Financial_accounting {
+	t.Helper()
+	s := test_company(t, driver, "")
+	s.accounts = append(s.accounts,
+		account{false, "", "cash_and_cash_equivalents", "usd cash", false, "", account_metadata{currency: "USD"}},
+		account{true, "", "income_statement", "exchange difference", false, "", account_metadata{}})
+	s.exchange_difference = "exchange difference"
+	s.initialize()
+	check(t, s.set_exchange_rate("USD", test_day, number(4)))
+	check(t, s.set_exchange_rate("USD", test_day.AddDate(0, 0, 5), number(5)))
+	return s
+}
+
+// the revaluation of date takes the balance and the rate before date like account_balance does
+func TestRevaluationCutOff(t *testing.T) {
+	for _, driver := range []string{"memory", "sqlite3"} {
+		s := currency_company(t, driver)
+		_, err := s.foreign_entry([]foreign_line{{"capital", "", number(800), number(0), "", number(0)}, {"usd cash", "USD", number(200), number(0), "", number(0)}}, true, test_day, "", "", "clerk")
+		check(t, err)
+		entry, err := s.revalue_currencies(test_day.AddDate(0, 0, 5), "boss")
+		check(t, err)
+		if len(entry) != 0 {
+			t.Errorf("%s: the rate of the date of the revaluation is used %v", driver, entry)
+		}
+		revaluation_date := test_day.AddDate(0, 0, 6)
+		entry, err = s.revalue_currencies(revaluation_date, "boss")
+		check(t, err)
+		if len(entry) != 2 || !entry[0].value.Equal(number(200)) || !entry[0].date.Before(revaluation_date) {
+			t.Fatalf("%s: the revaluation should be 200 before %s not %v", driver, revaluation_date, entry)
+		}
+		balance, err := s.db.account_balance("usd cash", revaluation_date)
+		check(t, err)
+		if !balance.Equal(number(1000)) {
+			t.Errorf("%s: the balance of usd cash before the revaluation date is %s not 1000", driver, balance)
+		}
+		_, err = s.foreign_entry([]foreign_line{{"capital", "", number(500), number(0), "", number(0)}, {"usd cash", "USD", number(100), number(0), "", number(0)}}, true, revaluation_date, "", "", "clerk")
+		check(t, err)
+		entry, err = s.revalue_currencies(revaluation_date, "boss")
+		check(t, err)
+		if len(entry) != 0 {
+			t.Errorf("%s: running the revaluation again on the same date posted %v", driver, entry)
+		}
+	}
+}
+
+// the lines of the same account and rate are grouped and the cent that the rounding makes goes to exchange difference
+func TestForeignEntryRounding(t *testing.T) {
+	for _, driver := range []string{"memory", "sqlite3"} {
+		s := currency_company(t, driver)
+		entry, err := s.foreign_entry([]foreign_line{
+			{"usd cash", "USD", number(0.5), number(0), "", number(2)},
+			{"usd cash", "USD", number(0.5024), number(0), "", number(2)},
+			{"usd cash", "USD", number(1.0024), number(0), "", number(2.000001)},
+			{"capital", "", number(4.01), number(0), "", number(0)},
+			{"rent", "", number(0), number(0), "", number(0)},
+		}, true, test_day, "", "", "clerk")
+		check(t, err)
+		if len(entry) != 4 {
+			t.Fatalf("%s: the entry should have 4 lines not %v", driver, entry)
+		}
+		if difference := entry[3]; difference.account != "exchange difference" || !difference.value.Equal(number(-0.01)) {
+			t.Errorf("%s: the rounding difference should be -0.01 in exchange difference not %v", driver, difference)
+		}
+		expect_balance(t, s, "usd cash", 4)
+		_, err = s.foreign_entry([]foreign_line{{"usd cash", "USD", number(1), number(0), "", number(2)}, {"capital", "", number(2.01), number(0), "", number(0)}}, true, test_day, "", "", "clerk")
+		if _, ok := err.(ErrUnbalancedEntry); !ok {
+			t.Errorf("%s: the entry that is not balanced before rounding should be ErrUnbalancedEntry not %v", driver, err)
+		}
+	}
+}
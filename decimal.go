@@ -0,0 +1,40 @@
+package main
+
+import "github.com/shopspring/decimal"
+
+// the values, prices and quantities are decimal so the entries balance to the last place and never fail because of float drift.
+// sqlite has no decimal type so there they are saved as text and read back by decimal.NewFromString
+
+const (
+	default_value_places    = 2
+	default_quantity_places = 6
+)
+
+func (s Financial_accounting) round_value(value decimal.Decimal) decimal.Decimal {
+	return value.Round(s.value_places)
+}
+
+func (s Financial_accounting) round_quantity(quantity decimal.Decimal) decimal.Decimal {
+	return quantity.Round(s.quantity_places)
+}
+
+// round_entry rounds the values and quantities of the entry to the places of the company
+func (s Financial_accounting) round_entry(array_of_entry []Account_value_quantity_barcode) {
+	for index, entry := range array_of_entry {
+		array_of_entry[index].value = s.round_value(entry.value)
+		array_of_entry[index].quantity = s.round_quantity(entry.quantity)
+	}
+}
+
+// divide returns 0 when b is 0 because decimal has no Inf or NaN, the ratios of the statements use it
+func divide(a, b decimal.Decimal) decimal.Decimal {
+	if b.IsZero() {
+		return decimal.Zero
+	}
+	return a.Div(b)
+}
+
+// sum_decimal adds the value to m[key], the missing keys of the statement maps are zero
+func sum_decimal(m map[string]decimal.Decimal, key string, value decimal.Decimal) {
+	m[key] = m[key].Add(value)
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// a draft is an entry that is saved without changing the balances or the inventory, another employee approves it
+// and then it is posted by post_entry or rejects it with a comment. the entries that match an approval rule of the
+// company can't be posted by journal_entry directly, they should be saved as drafts and approved
+
+type draft struct {
+	draft_number                     int
+	lines                            []Account_value_quantity_barcode // as they are entered, they are completed when the draft is approved
+	auto_completion                  bool
+	date, entry_expair               time.Time
+	adjusting_method                 string
+	array_day_start_end              []day_start_end // the days and hours of the adjusted drafts, empty is every day of the week
+	description, name, employee_name string          // employee_name is the employee that saved the draft
+	entry_date                       time.Time
+	status                           string // draft, approved or rejected
+	reviewer_name                    string
+	review_date                      time.Time
+	comment                          string // why the draft is rejected
+	entry_number                     int    // the entry that is posted when the draft is approved
+}
+
+// approval_rule matches the entries that have a line in account or its sons with an absolute value of min_value or more,
+// the empty account is every account. approvers are the employees that can approve them, empty means anyone but the
+// employee that saved the draft
+type approval_rule struct {
+	account   string
+	min_value decimal.Decimal
+	approvers []string
+}
+
+// save_draft checks the entry like journal_entry does without posting it then saves it as a draft and returns its number
+func (s Financial_accounting) save_draft(array_of_entry []Account_value_quantity_barcode, auto_completion bool, date, entry_expair time.Time, adjusting_method,
+	description, name, employee_name string, array_day_start_end []day_start_end) (int, error) {
+	if employee_name == "" {
+		return 0, ErrApprovalRequired{"the employee_name of the draft can't be empty"}
+	}
+	var draft_number int
+	err := s.db.transaction(func(tx storage) error {
+		err := tx.lock_ledger()
+		if err != nil {
+			return err
+		}
+		lines := append([]Account_value_quantity_barcode{}, array_of_entry...)
+		days := append([]day_start_end{}, array_day_start_end...)
+		poster := s
+		poster.db = tx
+		_, err = poster.journal_entry(append([]Account_value_quantity_barcode{}, lines...), false, auto_completion, date, entry_expair, adjusting_method, description, name, employee_name,
+			append([]day_start_end{}, days...))
+		if err != nil {
+			return err
+		}
+		drafts, err := tx.drafts()
+		if err != nil {
+			return err
+		}
+		for _, a := range drafts {
+			if a.draft_number > draft_number {
+				draft_number = a.draft_number
+			}
+		}
+		draft_number++
+		return tx.insert_draft(draft{draft_number, lines, auto_completion, date, entry_expair, adjusting_method, days, description, name, employee_name, Now, "draft", "", time.Time{}, "", 0})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return draft_number, nil
+}
+
+// drafts returns the drafts in the status or all of them if the status is empty
+func (s Financial_accounting) drafts(status string) ([]draft, error) {
+	drafts, err := s.db.drafts()
+	if err != nil {
+		return nil, err
+	}
+	var filtered []draft
+	for _, a := range drafts {
+		if status == "" || a.status == status {
+			filtered = append(filtered, a)
+		}
+	}
+	return filtered, nil
+}
+
+// approve_draft posts the draft by employee_name so the periods are checked for the approver and the entry is saved by them,
+// employee_name should not be the employee that saved it and should be in the approvers of every rule that the posted entry matches
+func (s Financial_accounting) approve_draft(draft_number int, employee_name string) ([]journal_tag, error) {
+	var entry []journal_tag
+	err := s.db.transaction(func(tx storage) error {
+		err := tx.lock_ledger()
+		if err != nil {
+			return err
+		}
+		d, err := s.draft_to_review(tx, draft_number, employee_name)
+		if err != nil {
+			return err
+		}
+		// the rules are checked here with the approver instead of journal_entry
+		poster := s
+		poster.db = tx
+		entry, err = poster.post_entry(append([]Account_value_quantity_barcode{}, d.lines...), true, d.auto_completion, d.date, d.entry_expair, d.adjusting_method, d.description, d.name, employee_name,
+			append([]day_start_end{}, d.array_day_start_end...), false)
+		if err != nil {
+			return err
+		}
+		var lines []Account_value_quantity_barcode
+		for _, a := range entry {
+			lines = append(lines, Account_value_quantity_barcode{Account: a.account, value: a.value})
+		}
+		for _, rule := range s.matched_approval_rules(lines) {
+			if len(rule.approvers) != 0 && !IS_IN(employee_name, rule.approvers) {
+				return ErrApprovalRequired{fmt.Sprint(employee_name, " can't approve the draft ", draft_number, " because it has ", rule.account, " of ", rule.min_value, " or more, it should be approved by one of ", rule.approvers)}
+			}
+		}
+		return tx.set_draft_review(draft_number, "approved", employee_name, Now, "", entry[0].entry_number)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// reject_draft keeps the draft with the comment so the employee that saved it knows why, it can't be approved after that
+func (s Financial_accounting) reject_draft(draft_number int, employee_name, comment string) error {
+	if comment == "" {
+		return ErrApprovalRequired{fmt.Sprint("the comment of rejecting the draft ", draft_number, " can't be empty")}
+	}
+	return s.db.transaction(func(tx storage) error {
+		err := tx.lock_ledger()
+		if err != nil {
+			return err
+		}
+		_, err = s.draft_to_review(tx, draft_number, employee_name)
+		if err != nil {
+			return err
+		}
+		return tx.set_draft_review(draft_number, "rejected", employee_name, Now, comment, 0)
+	})
+}
+
+func (s Financial_accounting) draft_to_review(tx storage, draft_number int, employee_name string) (draft, error) {
+	drafts, err := tx.drafts()
+	if err != nil {
+		return draft{}, err
+	}
+	for _, d := range drafts {
+		if d.draft_number != draft_number {
+			continue
+		}
+		switch {
+		case d.status != "draft":
+			return draft{}, ErrApprovalRequired{fmt.Sprint("the draft ", draft_number, " is already ", d.status, " by ", d.reviewer_name)}
+		case employee_name == "" || employee_name == d.employee_name:
+			return draft{}, ErrApprovalRequired{fmt.Sprint("the draft ", draft_number, " should be reviewed by an employee other than ", d.employee_name)}
+		}
+		return d, nil
+	}
+	return draft{}, ErrDraftNotFound{draft_number}
+}
+
+// check_approval_rules is used by journal_entry, the entries that match a rule should be saved by save_draft
+func (s Financial_accounting) check_approval_rules(array_of_entry []Account_value_quantity_barcode) error {
+	rules := s.matched_approval_rules(array_of_entry)
+	if len(rules) == 0 {
+		return nil
+	}
+	return ErrApprovalRequired{fmt.Sprint("the entry has ", rules[0].account, " of ", rules[0].min_value, " or more so it should be saved as a draft and approved")}
+}
+
+func (s Financial_accounting) matched_approval_rules(array_of_entry []Account_value_quantity_barcode) []approval_rule {
+	var rules []approval_rule
+	for _, rule := range s.approval_rules {
+		for _, entry := range array_of_entry {
+			if s.is_father(rule.account, entry.Account) && entry.value.Abs().GreaterThanOrEqual(rule.min_value) {
+				rules = append(rules, rule)
+				break
+			}
+		}
+	}
+	return rules
+}
+
+func (s Financial_accounting) approval_rule_violations() []violation {
+	var violations []violation
+	for _, rule := range s.approval_rules {
+		if rule.account != "" && !s.is_account(rule.account) {
+			violations = append(violations, violation{severity_error, rule.account, 0, fmt.Sprint(rule.account, " in approval_rules is not in the accounts")})
+		}
+		if rule.min_value.IsNegative() {
+			violations = append(violations, violation{severity_error, rule.account, 0, fmt.Sprint("the min_value of the approval rule of [", rule.account, "] can't be negative")})
+		}
+	}
+	return violations
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// the days of the adjusted draft are saved with it and the approved entry is posted by the approver on them only
+func TestDraftDays(t *testing.T) {
+	for _, driver := range []string{"memory", "sqlite3"} {
+		s := test_company(t, driver, "")
+		s.initialize()
+		post(t, s, test_day, line("cash", 1000, 1000), line("capital", 1000, 1000))
+		days := []day_start_end{{"friday", 0, 0, 23, 59}}
+		draft_number, err := s.save_draft([]Account_value_quantity_barcode{line("rent", 70, 70), line("cash", -70, -70)}, true, test_day, test_day.AddDate(0, 0, 14), "linear", "", "", "clerk", days)
+		check(t, err)
+		drafts, err := s.drafts("draft")
+		check(t, err)
+		if len(drafts) != 1 || len(drafts[0].array_day_start_end) != 1 || drafts[0].array_day_start_end[0] != days[0] {
+			t.Fatalf("%s: the days of the draft are not saved %v", driver, drafts)
+		}
+		entry, err := s.approve_draft(draft_number, "boss")
+		check(t, err)
+		if entry[0].employee_name != "boss" {
+			t.Errorf("%s: the draft is posted by %s not boss", driver, entry[0].employee_name)
+		}
+		for _, tag := range entry {
+			if tag.date.Weekday() != time.Friday {
+				t.Errorf("%s: the approved entry has a line on %s", driver, tag.date.Weekday())
+			}
+		}
+		expect_balance(t, s, "cash", 930)
+	}
+}
+
+// the draft is posted by the approver so a period manager can approve it after its period is closed
+func TestApproveDraftInClosedPeriod(t *testing.T) {
+	s := test_company(t, "memory", "")
+	s.period_managers = []string{"boss"}
+	s.initialize()
+	post(t, s, test_day, line("cash", 1000, 1000), line("capital", 1000, 1000))
+	draft_number, err := s.save_draft([]Account_value_quantity_barcode{line("rent", 10, 10), line("cash", -10, -10)}, false, test_day, time.Time{}, "", "", "", "clerk", nil)
+	check(t, err)
+	check(t, s.close_period(test_day, test_day.AddDate(0, 1, 0), "boss"))
+	if _, err := s.approve_draft(draft_number, "auditor"); err == nil {
+		t.Error("the approver that is not a period manager should not post in the closed period")
+	}
+	entry, err := s.approve_draft(draft_number, "boss")
+	check(t, err)
+	if entry[0].employee_name != "boss" {
+		t.Errorf("the draft is posted by %s not boss", entry[0].employee_name)
+	}
+	expect_balance(t, s, "cash", 990)
+}
+
+// the templates and the recurring entries are refused by the approval rules like journal_entry, the lines of the template
+// are saved as a draft and approved. the reclassifications are not refused because they move the balances of the accounts
+func TestApprovalRulesForTemplatesAndRecurring(t *testing.T) {
+	s := test_company(t, "memory", "")
+	s.transaction_templates = []transaction_template{{"rent", nil, []template_line{{"rent", "", "fixed", "60", "", ""}, {"cash", "", "fixed", "-60", "", ""}}}}
+	s.initialize()
+	post(t, s, test_day, line("cash", 1000, 1000), line("capital", 1000, 1000))
+	rent := recurring_entry{"rent", []Account_value_quantity_barcode{line("rent", 60, 60), line("cash", -60, -60)}, "monthly", 0, test_day, time.Time{}, time.Time{}, "", "", "clerk"}
+	check(t, s.create_recurring_entry(rent))
+	s.approval_rules = []approval_rule{{"rent", number(50), nil}}
+	_, err := s.journal_entry([]Account_value_quantity_barcode{line("rent", 60, 60), line("cash", -60, -60)}, true, false, test_day, time.Time{}, "", "", "", "clerk", nil)
+	if _, ok := err.(ErrApprovalRequired); !ok {
+		t.Fatalf("the entry of the employee should be ErrApprovalRequired not %v", err)
+	}
+	_, err = s.template_entry("rent", nil, true, test_day, "", "", "clerk")
+	if _, ok := err.(ErrApprovalRequired); !ok {
+		t.Errorf("the template entry should be ErrApprovalRequired not %v", err)
+	}
+	rent.title = "rent 2"
+	if _, ok := s.create_recurring_entry(rent).(ErrApprovalRequired); !ok {
+		t.Error("the recurring entry that matches a rule should not be created")
+	}
+	// the rule is added after the recurring entry is created so it is refused when it is posted
+	runs, err := s.post_recurring_entries(test_day.AddDate(0, 0, 1))
+	if _, ok := err.(ErrApprovalRequired); !ok || len(runs) != 0 {
+		t.Errorf("the recurring entry should be ErrApprovalRequired not %v %v", runs, err)
+	}
+	expect_balance(t, s, "rent", 0)
+
+	preview, err := s.template_entry("rent", nil, false, test_day, "", "", "clerk")
+	check(t, err)
+	var lines []Account_value_quantity_barcode
+	for _, a := range preview {
+		lines = append(lines, Account_value_quantity_barcode{Account: a.account, value: a.value, quantity: a.quantity})
+	}
+	draft_number, err := s.save_draft(lines, false, test_day, time.Time{}, "", "", "", "clerk", nil)
+	check(t, err)
+	_, err = s.approve_draft(draft_number, "boss")
+	check(t, err)
+	expect_balance(t, s, "rent", 60)
+	check(t, s.reclassify_account("rent", "interest_expense", test_day.AddDate(0, 0, 10), "boss"))
+	expect_balance(t, s, "interest_expense", 60)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// the errors that journal_entry, reverse_entry and financial_statements can return.
+// use errors.As to get the details out of them
+
+type ErrUnbalancedEntry struct {
+	Difference decimal.Decimal // debit-credit, if >0 the debit is overstated else the credit is overstated
+	Entry      []Account_value_quantity_barcode
+}
+
+type ErrInvalidEntry struct {
+	Entry  []Account_value_quantity_barcode
+	Reason string
+}
+
+type ErrInsufficientInventory struct {
+	Account, Barcode     string
+	Requested, Available decimal.Decimal
+}
+
+type ErrNegativeBalance struct {
+	Account        string
+	Balance, Value decimal.Decimal
+}
+
+type ErrUnknownAccount struct {
+	Account string
+}
+
+type ErrInactiveAccount struct {
+	Account string
+}
+
+type ErrUnknownBarcode struct {
+	Barcode string
+}
+
+type ErrInvalidSchedule struct {
+	Reason string
+}
+
+type ErrInvalidPeriod struct {
+	Start_date, End_date time.Time
+}
+
+type ErrEntryNotFound struct {
+	Entry_number uint
+}
+
+type ErrInvalidConfig struct {
+	Reason string
+}
+
+type ErrUnknownTemplate struct {
+	Template string
+}
+
+type ErrApprovalRequired struct {
+	Reason string
+}
+
+type ErrDraftNotFound struct {
+	Draft_number int
+}
+
+type ErrClosedPeriod struct {
+	Date, Start_date, End_date time.Time
+	Status                     string
+}
+
+type ErrNotAuthorized struct {
+	Employee_name, Operation string
+}
+
+type ErrUnknownRate struct {
+	Currency string
+	Date     time.Time
+}
+
+func (e ErrUnbalancedEntry) Error() string {
+	return fmt.Sprint(e.Difference, " not equal 0 if the number>0 it means debit overstated else credit overstated debit-credit should equal zero ", e.Entry)
+}
+
+func (e ErrInvalidEntry) Error() string {
+	return fmt.Sprint(e.Reason, " ", e.Entry)
+}
+
+func (e ErrInsufficientInventory) Error() string {
+	return fmt.Sprint("you order ", e.Requested, " but you have ", e.Available, " ", e.Account, " with barcode ", e.Barcode)
+}
+
+func (e ErrNegativeBalance) Error() string {
+	return fmt.Sprint("you cant enter ", e.Value, " in ", e.Account, " because you have ", e.Balance, " and that will make the balance negative ", e.Balance.Add(e.Value), " and that you just can do it in equity_normal accounts not other accounts")
+}
+
+func (e ErrUnknownAccount) Error() string {
+	return fmt.Sprint(e.Account, " is not in the accounts")
+}
+
+func (e ErrInactiveAccount) Error() string {
+	return fmt.Sprint(e.Account, " is deactivated and you can't enter it in new entries")
+}
+
+func (e ErrUnknownBarcode) Error() string {
+	return fmt.Sprint("the barcode is wrong for ", e.Barcode)
+}
+
+func (e ErrInvalidSchedule) Error() string {
+	return e.Reason
+}
+
+func (e ErrInvalidPeriod) Error() string {
+	return fmt.Sprint("please enter the start_date<=end_date ", e.Start_date, " ", e.End_date)
+}
+
+func (e ErrEntryNotFound) Error() string {
+	return fmt.Sprint("the entry number ", e.Entry_number, " not exist")
+}
+
+func (e ErrInvalidConfig) Error() string {
+	return e.Reason
+}
+
+func (e ErrUnknownTemplate) Error() string {
+	return fmt.Sprint("the transaction template ", e.Template, " is not in the config")
+}
+
+func (e ErrApprovalRequired) Error() string {
+	return e.Reason
+}
+
+func (e ErrDraftNotFound) Error() string {
+	return fmt.Sprint("the draft number ", e.Draft_number, " not exist")
+}
+
+func (e ErrClosedPeriod) Error() string {
+	return fmt.Sprint("the date ", e.Date, " is in the ", e.Status, " period from ", e.Start_date, " to ", e.End_date)
+}
+
+func (e ErrNotAuthorized) Error() string {
+	return fmt.Sprint("[", e.Employee_name, "] is not in period_managers so it can't ", e.Operation)
+}
+
+func (e ErrUnknownRate) Error() string {
+	return fmt.Sprint("there is no rate of ", e.Currency, " on or before ", e.Date, " add it by set_exchange_rate")
+}
@@ -2,4 +2,10 @@ module anti_accountants
 
 go 1.17
 
-require github.com/go-sql-driver/mysql v1.6.0
+require (
+	github.com/go-sql-driver/mysql v1.6.0
+	github.com/lib/pq v1.10.9
+	github.com/mattn/go-sqlite3 v1.14.16
+	github.com/shopspring/decimal v1.3.1
+	gopkg.in/yaml.v3 v3.0.1
+)
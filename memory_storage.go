@@ -0,0 +1,705 @@
+package main
+
+import (
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// memory_storage keeps the journal and inventory in memory so the whole pipeline can run without a database server.
+// a transaction works on a copy of the tables and the copy replaces the tables when it commits
+type memory_storage struct {
+	*memory_database
+	in_transaction bool
+}
+
+type memory_database struct {
+	mutex             sync.Mutex
+	journal           []journal_tag
+	entries           []entry_header
+	inventory         []journal_tag
+	last_id           int
+	last_entry_number int
+	snapshot_dates    []time.Time
+	snapshots         []balance_snapshot
+	accounts          []account
+	account_changes   []account_change
+	recurring         []recurring_entry
+	recurring_runs    []recurring_run
+	drafts            []draft
+	periods           []period
+	period_changes    []period_change
+	year_closings     []year_closing
+	exchange_rates    []exchange_rate
+}
+
+func open_memory() storage {
+	return memory_storage{&memory_database{}, false}
+}
+
+func (s memory_storage) transaction(f func(tx storage) error) error {
+	if s.in_transaction {
+		return f(s)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	tx := s.copy()
+	err := f(memory_storage{tx, true})
+	if err != nil {
+		return err
+	}
+	s.memory_database.journal, s.memory_database.inventory, s.memory_database.last_id = tx.journal, tx.inventory, tx.last_id
+	s.entries = tx.entries
+	s.last_entry_number = tx.last_entry_number
+	s.memory_database.snapshot_dates, s.snapshots = tx.snapshot_dates, tx.snapshots
+	s.memory_database.accounts, s.memory_database.account_changes = tx.accounts, tx.account_changes
+	s.recurring, s.memory_database.recurring_runs = tx.recurring, tx.recurring_runs
+	s.memory_database.drafts = tx.drafts
+	s.memory_database.periods, s.memory_database.period_changes = tx.periods, tx.period_changes
+	s.memory_database.year_closings, s.memory_database.exchange_rates = tx.year_closings, tx.exchange_rates
+	return nil
+}
+
+// read_only works on a copy of the tables so the transactions that post don't wait for it to finish
+func (s memory_storage) read_only(f func(tx storage) error) error {
+	if s.in_transaction {
+		return f(s)
+	}
+	s.mutex.Lock()
+	tx := s.copy()
+	s.mutex.Unlock()
+	return f(memory_storage{tx, true})
+}
+
+// copy should be called with the mutex locked
+func (s memory_storage) copy() *memory_database {
+	return &memory_database{
+		journal:           append([]journal_tag{}, s.memory_database.journal...),
+		entries:           append([]entry_header{}, s.entries...),
+		inventory:         append([]journal_tag{}, s.inventory...),
+		last_id:           s.last_id,
+		last_entry_number: s.last_entry_number,
+		snapshot_dates:    append([]time.Time{}, s.memory_database.snapshot_dates...),
+		snapshots:         append([]balance_snapshot{}, s.snapshots...),
+		accounts:          append([]account{}, s.memory_database.accounts...),
+		account_changes:   append([]account_change{}, s.memory_database.account_changes...),
+		recurring:         append([]recurring_entry{}, s.recurring...),
+		recurring_runs:    append([]recurring_run{}, s.memory_database.recurring_runs...),
+		drafts:            append([]draft{}, s.memory_database.drafts...),
+		periods:           append([]period{}, s.memory_database.periods...),
+		period_changes:    append([]period_change{}, s.memory_database.period_changes...),
+		year_closings:     append([]year_closing{}, s.memory_database.year_closings...),
+		exchange_rates:    append([]exchange_rate{}, s.memory_database.exchange_rates...),
+	}
+}
+
+// lock is used by the methods that run out of a transaction, inside one the tables belong to the transaction only
+func (s memory_storage) lock() func() {
+	if s.in_transaction {
+		return func() {}
+	}
+	s.mutex.Lock()
+	return s.mutex.Unlock
+}
+
+func (s memory_storage) migrate(date_layout []string) error {
+	return nil
+}
+
+func (s memory_storage) journal() ([]journal_tag, error) {
+	defer s.lock()()
+	journal := append([]journal_tag{}, s.memory_database.journal...)
+	sort.SliceStable(journal, func(i, j int) bool {
+		if journal[i].date.Equal(journal[j].date) {
+			return journal[i].entry_number < journal[j].entry_number
+		}
+		return journal[i].date.Before(journal[j].date)
+	})
+	return journal, nil
+}
+
+func (s memory_storage) select_journal(where func(tag journal_tag) bool) []journal_tag {
+	defer s.lock()()
+	var journal []journal_tag
+	for _, tag := range s.memory_database.journal {
+		if where(tag) {
+			journal = append(journal, tag)
+		}
+	}
+	sort.SliceStable(journal, func(i, j int) bool { return journal[i].date.Before(journal[j].date) })
+	return journal
+}
+
+func (s memory_storage) journal_of_entry_number(entry_number uint) ([]journal_tag, error) {
+	return s.select_journal(func(tag journal_tag) bool { return tag.entry_number == int(entry_number) }), nil
+}
+
+func (s memory_storage) journal_of_entry_number_between(entry_number uint, start_date, end_date time.Time) ([]journal_tag, error) {
+	return s.select_journal(func(tag journal_tag) bool {
+		return tag.date.After(start_date) && tag.date.Before(end_date) && tag.entry_number == int(entry_number)
+	}), nil
+}
+
+func (s memory_storage) journal_of_account_between(account string, start_date, end_date time.Time) ([]journal_tag, error) {
+	return s.select_journal(func(tag journal_tag) bool {
+		return tag.date.After(start_date) && tag.date.Before(end_date) && tag.account == account
+	}), nil
+}
+
+func (s memory_storage) journal_between_dates(start_date, end_date time.Time) ([]journal_tag, error) {
+	journal, err := s.journal()
+	var between []journal_tag
+	for _, tag := range journal {
+		if !tag.date.Before(start_date) && tag.date.Before(end_date) {
+			between = append(between, tag)
+		}
+	}
+	return between, err
+}
+
+func (s memory_storage) insert_journal(tag journal_tag) error {
+	defer s.lock()()
+	s.last_id++
+	tag.id = s.last_id
+	s.memory_database.journal = append(s.memory_database.journal, tag)
+	return nil
+}
+
+func (s memory_storage) header_of_entry_number(entry_number uint) (entry_header, error) {
+	defer s.lock()()
+	for _, header := range s.entries {
+		if header.entry_number == int(entry_number) {
+			return header, nil
+		}
+	}
+	return entry_header{}, sql.ErrNoRows
+}
+
+func (s memory_storage) insert_entry_header(header entry_header) error {
+	defer s.lock()()
+	s.entries = append(s.entries, header)
+	return nil
+}
+
+func (s memory_storage) delete_entry_header(entry_number int) error {
+	defer s.lock()()
+	var entries []entry_header
+	for _, header := range s.entries {
+		if header.entry_number != entry_number {
+			entries = append(entries, header)
+		}
+	}
+	s.entries = entries
+	return nil
+}
+
+func (s memory_storage) set_reverse(id int) error {
+	defer s.lock()()
+	for index, tag := range s.memory_database.journal {
+		if tag.id == id {
+			s.memory_database.journal[index].reverse = true
+		}
+	}
+	return nil
+}
+
+func (s memory_storage) delete_journal(id int) error {
+	defer s.lock()()
+	s.memory_database.journal = delete_by_id(s.memory_database.journal, id)
+	return nil
+}
+
+// the transactions hold the mutex until they end so they are already one after the other
+func (s memory_storage) lock_ledger() error {
+	return nil
+}
+
+func (s memory_storage) next_entry_numbers(count int) (int, error) {
+	defer s.lock()()
+	s.last_entry_number += count
+	return s.last_entry_number - count + 1, nil
+}
+
+func (s memory_storage) account_balance(account string, before_date time.Time) (decimal.Decimal, error) {
+	defer s.lock()()
+	var balance decimal.Decimal
+	for _, tag := range s.memory_database.journal {
+		if tag.account == account && tag.date.Before(before_date) {
+			balance = balance.Add(tag.value)
+		}
+	}
+	return balance, nil
+}
+
+func (s memory_storage) account_of_barcode(barcode string) (string, error) {
+	defer s.lock()()
+	for _, tag := range s.memory_database.journal {
+		if tag.barcode == barcode {
+			return tag.account, nil
+		}
+	}
+	return "", sql.ErrNoRows
+}
+
+func (s memory_storage) is_account_used(account string) (bool, error) {
+	defer s.lock()()
+	for _, tag := range s.memory_database.journal {
+		if tag.account == account {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s memory_storage) rename_account(name, new_name string) error {
+	defer s.lock()()
+	for _, table := range [][]journal_tag{s.memory_database.journal, s.inventory} {
+		for index, tag := range table {
+			if tag.account == name {
+				table[index].account = new_name
+			}
+		}
+	}
+	for index, row := range s.snapshots {
+		if row.account == name {
+			s.snapshots[index].account = new_name
+		}
+		if row.account_flow == name {
+			s.snapshots[index].account_flow = new_name
+		}
+	}
+	// the lines are shared with the copy of the transaction so they are replaced not changed
+	for index, r := range s.recurring {
+		lines := append([]Account_value_quantity_barcode{}, r.lines...)
+		for indexb, line := range lines {
+			if line.Account == name {
+				lines[indexb].Account = new_name
+			}
+		}
+		s.recurring[index].lines = lines
+	}
+	for index, d := range s.memory_database.drafts {
+		lines := append([]Account_value_quantity_barcode{}, d.lines...)
+		for indexb, line := range lines {
+			if line.Account == name {
+				lines[indexb].Account = new_name
+			}
+		}
+		s.memory_database.drafts[index].lines = lines
+	}
+	return nil
+}
+
+func (s memory_storage) accounts() ([]account, error) {
+	defer s.lock()()
+	return append([]account{}, s.memory_database.accounts...), nil
+}
+
+func (s memory_storage) insert_account(a account) error {
+	defer s.lock()()
+	s.memory_database.accounts = append(s.memory_database.accounts, a)
+	return nil
+}
+
+func (s memory_storage) update_account(name string, a account) error {
+	defer s.lock()()
+	for index, b := range s.memory_database.accounts {
+		switch {
+		case b.name == name:
+			s.memory_database.accounts[index] = a
+		case b.father == name:
+			s.memory_database.accounts[index].father = a.name
+		}
+	}
+	return nil
+}
+
+func (s memory_storage) delete_account(name string) error {
+	defer s.lock()()
+	var accounts []account
+	for _, a := range s.memory_database.accounts {
+		if a.name != name {
+			accounts = append(accounts, a)
+		}
+	}
+	s.memory_database.accounts = accounts
+	return nil
+}
+
+func (s memory_storage) journal_of_account(account string) ([]journal_tag, error) {
+	return s.select_journal(func(tag journal_tag) bool { return tag.account == account }), nil
+}
+
+func (s memory_storage) move_journal(account, new_account string, start_date time.Time) error {
+	defer s.lock()()
+	for index, tag := range s.memory_database.journal {
+		if tag.account == account && !tag.date.Before(start_date) {
+			s.memory_database.journal[index].account = new_account
+		}
+	}
+	return nil
+}
+
+func (s memory_storage) account_changes() ([]account_change, error) {
+	defer s.lock()()
+	return append([]account_change{}, s.memory_database.account_changes...), nil
+}
+
+func (s memory_storage) insert_account_change(change account_change) error {
+	defer s.lock()()
+	s.last_id++
+	change.id = s.last_id
+	s.memory_database.account_changes = append(s.memory_database.account_changes, change)
+	return nil
+}
+
+func (s memory_storage) recurring_entries() ([]recurring_entry, error) {
+	defer s.lock()()
+	recurring := append([]recurring_entry{}, s.recurring...)
+	sort.SliceStable(recurring, func(i, j int) bool { return recurring[i].title < recurring[j].title })
+	for index, r := range recurring {
+		recurring[index].lines = append([]Account_value_quantity_barcode{}, r.lines...)
+	}
+	return recurring, nil
+}
+
+func (s memory_storage) insert_recurring_entry(r recurring_entry) error {
+	defer s.lock()()
+	r.lines = append([]Account_value_quantity_barcode{}, r.lines...)
+	s.recurring = append(s.recurring, r)
+	return nil
+}
+
+func (s memory_storage) set_recurring_next_date(title string, next_date time.Time) error {
+	defer s.lock()()
+	for index, r := range s.recurring {
+		if r.title == title {
+			s.recurring[index].next_date = next_date
+		}
+	}
+	return nil
+}
+
+func (s memory_storage) delete_recurring_entry(title string) error {
+	defer s.lock()()
+	for index, r := range s.recurring {
+		if r.title == title {
+			s.recurring = append(s.recurring[:index], s.recurring[index+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (s memory_storage) recurring_runs() ([]recurring_run, error) {
+	defer s.lock()()
+	return append([]recurring_run{}, s.memory_database.recurring_runs...), nil
+}
+
+func (s memory_storage) insert_recurring_run(run recurring_run) error {
+	defer s.lock()()
+	s.memory_database.recurring_runs = append(s.memory_database.recurring_runs, run)
+	return nil
+}
+
+func (s memory_storage) drafts() ([]draft, error) {
+	defer s.lock()()
+	drafts := append([]draft{}, s.memory_database.drafts...)
+	for index, d := range drafts {
+		drafts[index].lines = append([]Account_value_quantity_barcode{}, d.lines...)
+		drafts[index].array_day_start_end = append([]day_start_end{}, d.array_day_start_end...)
+	}
+	return drafts, nil
+}
+
+func (s memory_storage) insert_draft(d draft) error {
+	defer s.lock()()
+	d.lines = append([]Account_value_quantity_barcode{}, d.lines...)
+	d.array_day_start_end = append([]day_start_end{}, d.array_day_start_end...)
+	s.memory_database.drafts = append(s.memory_database.drafts, d)
+	return nil
+}
+
+func (s memory_storage) set_draft_review(draft_number int, status, reviewer_name string, review_date time.Time, comment string, entry_number int) error {
+	defer s.lock()()
+	for index, d := range s.memory_database.drafts {
+		if d.draft_number == draft_number {
+			s.memory_database.drafts[index].status, s.memory_database.drafts[index].reviewer_name = status, reviewer_name
+			s.memory_database.drafts[index].review_date, s.memory_database.drafts[index].comment = review_date, comment
+			s.memory_database.drafts[index].entry_number = entry_number
+		}
+	}
+	return nil
+}
+
+func (s memory_storage) periods() ([]period, error) {
+	defer s.lock()()
+	periods := append([]period{}, s.memory_database.periods...)
+	sort.SliceStable(periods, func(i, j int) bool { return periods[i].start_date.Before(periods[j].start_date) })
+	return periods, nil
+}
+
+func (s memory_storage) insert_period(p period) error {
+	defer s.lock()()
+	s.memory_database.periods = append(s.memory_database.periods, p)
+	return nil
+}
+
+func (s memory_storage) delete_period(start_date, end_date time.Time) error {
+	defer s.lock()()
+	var periods []period
+	for _, p := range s.memory_database.periods {
+		if !p.start_date.Equal(start_date) || !p.end_date.Equal(end_date) {
+			periods = append(periods, p)
+		}
+	}
+	s.memory_database.periods = periods
+	return nil
+}
+
+func (s memory_storage) period_changes() ([]period_change, error) {
+	defer s.lock()()
+	return append([]period_change{}, s.memory_database.period_changes...), nil
+}
+
+func (s memory_storage) insert_period_change(change period_change) error {
+	defer s.lock()()
+	s.last_id++
+	change.id = s.last_id
+	s.memory_database.period_changes = append(s.memory_database.period_changes, change)
+	return nil
+}
+
+func (s memory_storage) year_closings() ([]year_closing, error) {
+	defer s.lock()()
+	closings := append([]year_closing{}, s.memory_database.year_closings...)
+	for index, closing := range closings {
+		closings[index].entry_numbers = append([]int{}, closing.entry_numbers...)
+	}
+	return closings, nil
+}
+
+func (s memory_storage) insert_year_closing(closing year_closing) error {
+	defer s.lock()()
+	closing.entry_numbers = append([]int{}, closing.entry_numbers...)
+	s.memory_database.year_closings = append(s.memory_database.year_closings, closing)
+	return nil
+}
+
+func (s memory_storage) set_year_closing_undone(id int, employee_name string, date time.Time) error {
+	defer s.lock()()
+	for index, closing := range s.memory_database.year_closings {
+		if closing.id == id {
+			s.memory_database.year_closings[index].undone_by, s.memory_database.year_closings[index].undo_date = employee_name, date
+		}
+	}
+	return nil
+}
+
+func (s memory_storage) exchange_rates(currency string) ([]exchange_rate, error) {
+	defer s.lock()()
+	var rates []exchange_rate
+	for _, rate := range s.memory_database.exchange_rates {
+		if rate.currency == currency {
+			rates = append(rates, rate)
+		}
+	}
+	sort.SliceStable(rates, func(i, j int) bool { return rates[i].date.Before(rates[j].date) })
+	return rates, nil
+}
+
+func (s memory_storage) insert_exchange_rate(rate exchange_rate) error {
+	defer s.lock()()
+	s.memory_database.exchange_rates = append(s.memory_database.exchange_rates, rate)
+	return nil
+}
+
+func (s memory_storage) delete_exchange_rate(currency string, date time.Time) error {
+	defer s.lock()()
+	var rates []exchange_rate
+	for _, rate := range s.memory_database.exchange_rates {
+		if rate.currency != currency || !rate.date.Equal(date) {
+			rates = append(rates, rate)
+		}
+	}
+	s.memory_database.exchange_rates = rates
+	return nil
+}
+
+func (s memory_storage) inventory_accounts() ([]string, error) {
+	defer s.lock()()
+	var accounts []string
+	for _, tag := range s.inventory {
+		accounts = append(accounts, tag.account)
+	}
+	return accounts, nil
+}
+
+func (s memory_storage) inventory_layers(account, barcode, order_by_date_asc_or_desc string) ([]journal_tag, error) {
+	defer s.lock()()
+	var inventory []journal_tag
+	for _, tag := range s.inventory {
+		if tag.quantity.IsPositive() && tag.account == account && tag.barcode == barcode {
+			inventory = append(inventory, tag)
+		}
+	}
+	sort.SliceStable(inventory, func(i, j int) bool {
+		a, b := inventory[i], inventory[j]
+		if order_by_date_asc_or_desc == "desc" {
+			a, b = b, a
+		}
+		if a.date.Equal(b.date) {
+			return a.id < b.id
+		}
+		return a.date.Before(b.date)
+	})
+	return inventory, nil
+}
+
+func (s memory_storage) inventory_layer_of_line(tag journal_tag) (journal_tag, error) {
+	defer s.lock()()
+	for index := len(s.inventory) - 1; index >= 0; index-- {
+		layer := s.inventory[index]
+		if layer.account == tag.account && layer.barcode == tag.barcode && layer.date.Equal(tag.date) {
+			return layer, nil
+		}
+	}
+	return journal_tag{}, sql.ErrNoRows
+}
+
+func (s memory_storage) insert_inventory(tag journal_tag) error {
+	defer s.lock()()
+	s.last_id++
+	tag.id = s.last_id
+	s.inventory = append(s.inventory, tag)
+	return nil
+}
+
+func (s memory_storage) set_inventory_layer_quantity(id int, quantity decimal.Decimal) error {
+	defer s.lock()()
+	for index, tag := range s.inventory {
+		if tag.id == id {
+			s.inventory[index].quantity = quantity
+		}
+	}
+	return nil
+}
+
+func (s memory_storage) delete_inventory_layer(id int) error {
+	defer s.lock()()
+	s.inventory = delete_by_id(s.inventory, id)
+	return nil
+}
+
+func (s memory_storage) delete_empty_inventory() error {
+	defer s.lock()()
+	var inventory []journal_tag
+	for _, tag := range s.inventory {
+		if !tag.quantity.IsZero() {
+			inventory = append(inventory, tag)
+		}
+	}
+	s.inventory = inventory
+	return nil
+}
+
+func (s memory_storage) weighted_average(account string) error {
+	defer s.lock()()
+	var value, quantity decimal.Decimal
+	for _, tag := range s.memory_database.journal {
+		if tag.account == account {
+			value = value.Add(tag.value)
+			quantity = quantity.Add(tag.quantity)
+		}
+	}
+	if quantity.IsZero() {
+		return nil
+	}
+	for index, tag := range s.inventory {
+		if tag.account == account {
+			s.inventory[index].price = value.Div(quantity)
+		}
+	}
+	return nil
+}
+
+func (s memory_storage) snapshot_dates() ([]time.Time, error) {
+	defer s.lock()()
+	return append([]time.Time{}, s.memory_database.snapshot_dates...), nil
+}
+
+func (s memory_storage) snapshot(date time.Time) ([]balance_snapshot, error) {
+	defer s.lock()()
+	var snapshot []balance_snapshot
+	for _, row := range s.snapshots {
+		if row.date.Equal(date) {
+			snapshot = append(snapshot, row)
+		}
+	}
+	return snapshot, nil
+}
+
+func (s memory_storage) insert_snapshot(date time.Time, rows []balance_snapshot) error {
+	defer s.lock()()
+	s.memory_database.snapshot_dates = append(s.memory_database.snapshot_dates, date)
+	for _, row := range rows {
+		row.date = date
+		s.snapshots = append(s.snapshots, row)
+	}
+	return nil
+}
+
+func (s memory_storage) add_to_snapshots(after time.Time, rows []balance_snapshot) error {
+	defer s.lock()()
+	for _, date := range s.memory_database.snapshot_dates {
+		if !date.After(after) {
+			continue
+		}
+		for _, row := range rows {
+			found := false
+			for index, a := range s.snapshots {
+				if a.date.Equal(date) && a.account_flow == row.account_flow && a.account == row.account && a.name == row.name {
+					s.snapshots[index].value = a.value.Add(row.value)
+					s.snapshots[index].quantity = a.quantity.Add(row.quantity)
+					found = true
+					break
+				}
+			}
+			if !found {
+				row.date = date
+				s.snapshots = append(s.snapshots, row)
+			}
+		}
+	}
+	return nil
+}
+
+func (s memory_storage) delete_snapshots(after time.Time) error {
+	defer s.lock()()
+	var dates []time.Time
+	for _, date := range s.memory_database.snapshot_dates {
+		if !date.After(after) {
+			dates = append(dates, date)
+		}
+	}
+	var snapshots []balance_snapshot
+	for _, row := range s.snapshots {
+		if !row.date.After(after) {
+			snapshots = append(snapshots, row)
+		}
+	}
+	s.memory_database.snapshot_dates, s.snapshots = dates, snapshots
+	return nil
+}
+
+func delete_by_id(table []journal_tag, id int) []journal_tag {
+	for index, tag := range table {
+		if tag.id == id {
+			return append(table[:index], table[index+1:]...)
+		}
+	}
+	return table
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryLedger(t *testing.T) {
+	ledger_scenario(t, test_company(t, "memory", ""))
+}
+
+func TestSqliteLedger(t *testing.T) {
+	ledger_scenario(t, test_company(t, "sqlite3", ""))
+}
+
+// the preview reads the memory and changes nothing in it
+func TestMemoryPreview(t *testing.T) {
+	s := test_company(t, "memory", "")
+	s.initialize()
+	post(t, s, test_day, line("cash", 1000, 1000), line("capital", 1000, 1000))
+	_, err := s.journal_entry([]Account_value_quantity_barcode{line("panadol", 100, 10), line("cash", -100, -100)}, false, false, test_day, time.Time{}, "", "", "", "clerk", nil)
+	check(t, err)
+	expect_balance(t, s, "cash", 1000)
+	layers, err := s.db.inventory_layers("panadol", "", "asc")
+	check(t, err)
+	if len(layers) != 0 {
+		t.Errorf("the preview added the inventory layers %v", layers)
+	}
+}
+
+// the transaction of the memory is thrown away when it fails
+func TestMemoryRollback(t *testing.T) {
+	s := test_company(t, "memory", "")
+	s.initialize()
+	post(t, s, test_day, line("cash", 1000, 1000), line("capital", 1000, 1000))
+	_, err := s.journal_entry([]Account_value_quantity_barcode{line("cash", -2000, -2000), line("capital", -2000, -2000)}, true, false, test_day, time.Time{}, "", "", "", "clerk", nil)
+	if _, ok := err.(ErrNegativeBalance); !ok {
+		t.Fatalf("the entry should be ErrNegativeBalance not %v", err)
+	}
+	journal, err := s.db.journal()
+	check(t, err)
+	if len(journal) != 2 {
+		t.Errorf("the journal has %d lines not 2", len(journal))
+	}
+}
+
+// the previews of journal_entry, save_draft and foreign_entry take no entry number
+func TestPreviewNumbers(t *testing.T) {
+	for _, driver := range []string{"memory", "sqlite3"} {
+		s := test_company(t, driver, "")
+		s.initialize()
+		post(t, s, test_day, line("cash", 1000, 1000), line("capital", 1000, 1000))
+		preview, err := s.journal_entry([]Account_value_quantity_barcode{line("rent", 10, 10), line("cash", -10, -10)}, false, false, test_day, time.Time{}, "", "", "", "clerk", nil)
+		check(t, err)
+		if preview[0].entry_number != 0 {
+			t.Errorf("the preview has the entry number %d not 0", preview[0].entry_number)
+		}
+		_, err = s.save_draft([]Account_value_quantity_barcode{line("rent", 10, 10), line("cash", -10, -10)}, false, test_day, time.Time{}, "", "", "", "clerk", nil)
+		check(t, err)
+		_, err = s.foreign_entry([]foreign_line{{"rent", "", number(10), number(10), "", number(0)}, {"cash", "", number(-10), number(-10), "", number(0)}}, false, test_day, "", "", "clerk")
+		check(t, err)
+		entry := post(t, s, test_day, line("rent", 10, 10), line("cash", -10, -10))
+		if entry[0].entry_number != 2 {
+			t.Errorf("%s: the second entry has the number %d not 2", driver, entry[0].entry_number)
+		}
+	}
+}
@@ -0,0 +1,477 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// column types that differ between the databases
+type column_types struct {
+	id, datetime, key_text, float, decimal string
+}
+
+type migration struct {
+	version     int
+	description string
+	up          func(tx sql_storage, types column_types, date_layout []string) error
+}
+
+// the migrations run in order and each one runs once, the last applied version is saved in schema_version.
+// never change a migration that is released, add a new one instead
+var migrations = []migration{
+	{1, "journal and inventory with text dates", migrate_text_tables},
+	{2, "datetime columns, primary keys and indexes for journal and inventory", migrate_datetime_columns},
+	{3, "sequence of the entry numbers", migrate_entry_number_sequence},
+	{4, "decimal value, price and quantity", migrate_decimal_columns},
+	{5, "balance snapshots", migrate_snapshots},
+	{6, "chart of accounts", migrate_accounts},
+	{7, "log of the changes of the chart of accounts", migrate_account_changes},
+	{8, "codes of the accounts", migrate_account_codes},
+	{9, "metadata of the accounts", migrate_account_metadata},
+	{10, "headers of the entries", migrate_entry_headers},
+	{11, "recurring entries and the log of their postings", migrate_recurring_entries},
+	{12, "drafts of the entries and their review", migrate_drafts},
+	{13, "closed and locked periods and the log of their changes", migrate_periods},
+	{14, "year closings and their entries", migrate_year_closings},
+	{15, "currency of the journal lines and the exchange rates", migrate_currencies},
+}
+
+// every migration runs in a transaction with its version row so postgres and sqlite apply it all or nothing.
+// mysql commits every create and alter table by itself, so when a migration fails there the tables and columns it made
+// before the failing statement stay without a version row, they should be dropped by hand before initialize runs it again
+func (s sql_storage) migrate(date_layout []string) error {
+	types := s.dialect.column_types()
+	_, err := s.exec("create table if not exists schema_version (version integer primary key,description text,applied_at " + types.datetime + ")")
+	if err != nil {
+		return err
+	}
+	version, err := s.schema_version()
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if m.version <= version {
+			continue
+		}
+		err = s.begin(func(tx sql_storage) error {
+			err := m.up(tx, types, date_layout)
+			if err != nil {
+				return err
+			}
+			_, err = tx.exec("insert into schema_version(version,description,applied_at) values (?,?,?)", m.version, m.description, time.Now().UTC())
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.version, m.description, err)
+		}
+	}
+	return nil
+}
+
+func (s sql_storage) schema_version() (int, error) {
+	var version sql.NullInt64
+	err := s.query_row("select max(version) from schema_version").Scan(&version)
+	return int(version.Int64), err
+}
+
+// the tables as they were before the schema had versions
+func migrate_text_tables(tx sql_storage, types column_types, date_layout []string) error {
+	_, err := tx.exec("create table if not exists journal (date text,entry_number integer,account text,value real,price real,quantity real,barcode text,entry_expair text,description text,name text,employee_name text,entry_date text,reverse bool)")
+	if err != nil {
+		return err
+	}
+	_, err = tx.exec("create table if not exists inventory (date text,account text,price real,quantity real,barcode text,entry_expair text,name text,employee_name text,entry_date text)")
+	return err
+}
+
+// the text dates were saved with time.Time.String() so they are parsed in go and copied to new tables
+func migrate_datetime_columns(tx sql_storage, types column_types, date_layout []string) error {
+	queries := []string{
+		"create table journal_new (id " + types.id + ",date " + types.datetime + " not null,entry_number integer not null,account " + types.key_text + " not null,value " + types.float + ",price " + types.float + ",quantity " + types.float + ",barcode " + types.key_text + ",entry_expair " + types.datetime + " null,description text,name " + types.key_text + ",employee_name " + types.key_text + ",entry_date " + types.datetime + ",reverse bool)",
+		"create table inventory_new (id " + types.id + ",date " + types.datetime + " not null,account " + types.key_text + " not null,price " + types.float + ",quantity " + types.float + ",barcode " + types.key_text + ",entry_expair " + types.datetime + " null,name " + types.key_text + ",employee_name " + types.key_text + ",entry_date " + types.datetime + ")",
+	}
+	for _, query := range queries {
+		_, err := tx.exec(query)
+		if err != nil {
+			return err
+		}
+	}
+
+	rows, err := tx.query("select date,entry_number,account,value,price,quantity,barcode,entry_expair,description,name,employee_name,entry_date,reverse from journal")
+	if err != nil {
+		return err
+	}
+	var journal []journal_tag
+	for rows.Next() {
+		var tag journal_tag
+		var date, account, barcode, entry_expair, description, name, employee_name, entry_date sql.NullString
+		var value, price, quantity decimal.NullDecimal
+		var reverse sql.NullBool
+		err = rows.Scan(&date, &tag.entry_number, &account, &value, &price, &quantity, &barcode, &entry_expair, &description, &name, &employee_name, &entry_date, &reverse)
+		if err == nil {
+			tag.date, err = parse_text_date(date.String, date_layout)
+		}
+		if err == nil {
+			tag.entry_expair, err = parse_text_date(entry_expair.String, date_layout)
+		}
+		if err == nil {
+			tag.entry_date, err = parse_text_date(entry_date.String, date_layout)
+		}
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		tag.account, tag.barcode, tag.description, tag.name, tag.employee_name = account.String, barcode.String, description.String, name.String, employee_name.String
+		tag.value, tag.price, tag.quantity, tag.reverse = value.Decimal, price.Decimal, quantity.Decimal, reverse.Bool
+		journal = append(journal, tag)
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return rows.Err()
+	}
+
+	rows, err = tx.query("select date,account,price,quantity,barcode,entry_expair,name,employee_name,entry_date from inventory")
+	if err != nil {
+		return err
+	}
+	var inventory []journal_tag
+	for rows.Next() {
+		var tag journal_tag
+		var date, account, barcode, entry_expair, name, employee_name, entry_date sql.NullString
+		var price, quantity decimal.NullDecimal
+		err = rows.Scan(&date, &account, &price, &quantity, &barcode, &entry_expair, &name, &employee_name, &entry_date)
+		if err == nil {
+			tag.date, err = parse_text_date(date.String, date_layout)
+		}
+		if err == nil {
+			tag.entry_expair, err = parse_text_date(entry_expair.String, date_layout)
+		}
+		if err == nil {
+			tag.entry_date, err = parse_text_date(entry_date.String, date_layout)
+		}
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		tag.account, tag.barcode, tag.name, tag.employee_name = account.String, barcode.String, name.String, employee_name.String
+		tag.price, tag.quantity = price.Decimal, quantity.Decimal
+		inventory = append(inventory, tag)
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return rows.Err()
+	}
+
+	for _, tag := range journal {
+		_, err = tx.exec("insert into journal_new(date,entry_number,account,value,price,quantity,barcode,entry_expair,description,name,employee_name,entry_date,reverse) values (?,?,?,?,?,?,?,?,?,?,?,?,?)",
+			tag.date.UTC(), tag.entry_number, tag.account, tag.value, tag.price, tag.quantity, tag.barcode,
+			null_time(tag.entry_expair), tag.description, tag.name, tag.employee_name, tag.entry_date.UTC(), tag.reverse)
+		if err != nil {
+			return err
+		}
+	}
+	for _, tag := range inventory {
+		_, err = tx.exec("insert into inventory_new(date,account,price,quantity,barcode,entry_expair,name,employee_name,entry_date) values (?,?,?,?,?,?,?,?,?)",
+			tag.date.UTC(), tag.account, tag.price, tag.quantity, tag.barcode, null_time(tag.entry_expair), tag.name, tag.employee_name, tag.entry_date.UTC())
+		if err != nil {
+			return err
+		}
+	}
+
+	queries = []string{
+		"drop table journal",
+		"drop table inventory",
+		"alter table journal_new rename to journal",
+		"alter table inventory_new rename to inventory",
+		"create index journal_date_entry_number on journal (date,entry_number)",
+		"create index journal_entry_number on journal (entry_number)",
+		"create index journal_account_date on journal (account,date)",
+		"create index journal_barcode on journal (barcode)",
+		"create index inventory_account_barcode_date on inventory (account,barcode,date)",
+	}
+	for _, query := range queries {
+		_, err = tx.exec(query)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the entry numbers was max(entry_number)+1 so the sequence starts from the last one used
+func migrate_entry_number_sequence(tx sql_storage, types column_types, date_layout []string) error {
+	_, err := tx.exec("create table entry_number_sequence (name " + types.key_text + " primary key,value integer not null)")
+	if err != nil {
+		return err
+	}
+	_, err = tx.exec("insert into entry_number_sequence(name,value) select ?,coalesce(max(entry_number),0) from journal", "journal")
+	return err
+}
+
+// sqlite can't change the type of a column and has no decimal type, so there the tables are made again with text columns
+// and the real values are copied to them as decimal strings that are read back by decimal.NewFromString
+func migrate_decimal_columns(tx sql_storage, types column_types, date_layout []string) error {
+	columns := map[string][]string{
+		"journal":   {"value", "price", "quantity"},
+		"inventory": {"price", "quantity"},
+	}
+	if tx.dialect.alter_column_type("journal", "value", types.decimal) == "" {
+		return copy_decimal_tables(tx, types)
+	}
+	for _, table := range []string{"journal", "inventory"} {
+		for _, column := range columns[table] {
+			_, err := tx.exec(tx.dialect.alter_column_type(table, column, types.decimal))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func copy_decimal_tables(tx sql_storage, types column_types) error {
+	tables := []struct {
+		name, create string
+		columns      []string
+		decimals     map[string]bool
+		indexes      []string
+	}{
+		{
+			"journal",
+			"create table journal_decimal (id " + types.id + ",date " + types.datetime + " not null,entry_number integer not null,account " + types.key_text + " not null,value " + types.decimal + ",price " + types.decimal + ",quantity " + types.decimal + ",barcode " + types.key_text + ",entry_expair " + types.datetime + " null,description text,name " + types.key_text + ",employee_name " + types.key_text + ",entry_date " + types.datetime + ",reverse bool)",
+			[]string{"id", "date", "entry_number", "account", "value", "price", "quantity", "barcode", "entry_expair", "description", "name", "employee_name", "entry_date", "reverse"},
+			map[string]bool{"value": true, "price": true, "quantity": true},
+			[]string{
+				"create index journal_date_entry_number on journal (date,entry_number)",
+				"create index journal_entry_number on journal (entry_number)",
+				"create index journal_account_date on journal (account,date)",
+				"create index journal_barcode on journal (barcode)",
+			},
+		},
+		{
+			"inventory",
+			"create table inventory_decimal (id " + types.id + ",date " + types.datetime + " not null,account " + types.key_text + " not null,price " + types.decimal + ",quantity " + types.decimal + ",barcode " + types.key_text + ",entry_expair " + types.datetime + " null,name " + types.key_text + ",employee_name " + types.key_text + ",entry_date " + types.datetime + ")",
+			[]string{"id", "date", "account", "price", "quantity", "barcode", "entry_expair", "name", "employee_name", "entry_date"},
+			map[string]bool{"price": true, "quantity": true},
+			[]string{"create index inventory_account_barcode_date on inventory (account,barcode,date)"},
+		},
+	}
+	for _, table := range tables {
+		_, err := tx.exec(table.create)
+		if err != nil {
+			return err
+		}
+		rows, err := tx.query("select " + strings.Join(table.columns, ",") + " from " + table.name)
+		if err != nil {
+			return err
+		}
+		var copied [][]interface{}
+		for rows.Next() {
+			row := make([]interface{}, len(table.columns))
+			pointers := make([]interface{}, len(table.columns))
+			for index := range row {
+				pointers[index] = &row[index]
+			}
+			err = rows.Scan(pointers...)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			for index, column := range table.columns {
+				if !table.decimals[column] {
+					continue
+				}
+				var number decimal.NullDecimal
+				err = number.Scan(row[index])
+				if err != nil {
+					rows.Close()
+					return err
+				}
+				row[index] = number
+			}
+			copied = append(copied, row)
+		}
+		rows.Close()
+		if rows.Err() != nil {
+			return rows.Err()
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(table.columns)), ",")
+		for _, row := range copied {
+			_, err = tx.exec("insert into "+table.name+"_decimal("+strings.Join(table.columns, ",")+") values ("+placeholders+")", row...)
+			if err != nil {
+				return err
+			}
+		}
+		queries := append([]string{"drop table " + table.name, "alter table " + table.name + "_decimal rename to " + table.name}, table.indexes...)
+		for _, query := range queries {
+			_, err = tx.exec(query)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func migrate_snapshots(tx sql_storage, types column_types, date_layout []string) error {
+	queries := []string{
+		"create table snapshot (date " + types.datetime + " not null primary key)",
+		"create table snapshot_balance (id " + types.id + ",date " + types.datetime + " not null,account_flow " + types.key_text + " not null,account " + types.key_text + " not null,name " + types.key_text + " not null,value " + types.decimal + ",quantity " + types.decimal + ")",
+		"create index snapshot_balance_date on snapshot_balance (date,account_flow,account,name)",
+	}
+	for _, query := range queries {
+		_, err := tx.exec(query)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the accounts are saved from the config of the company by initialize when the table is empty
+func migrate_accounts(tx sql_storage, types column_types, date_layout []string) error {
+	_, err := tx.exec("create table accounts (id " + types.id + ",name " + types.key_text + " not null unique,father " + types.key_text + " not null,is_credit bool not null,cost_flow_type " + types.key_text + " not null,inactive bool not null)")
+	return err
+}
+
+func migrate_account_changes(tx sql_storage, types column_types, date_layout []string) error {
+	_, err := tx.exec("create table account_changes (id " + types.id + ",date " + types.datetime + " not null,operation " + types.key_text + " not null,account " + types.key_text + " not null,new_account " + types.key_text + " not null,details text not null,employee_name " + types.key_text + " not null)")
+	return err
+}
+
+func migrate_account_codes(tx sql_storage, types column_types, date_layout []string) error {
+	_, err := tx.exec("alter table accounts add column code " + types.key_text + " not null default ''")
+	return err
+}
+
+func migrate_account_metadata(tx sql_storage, types column_types, date_layout []string) error {
+	for _, column := range []string{
+		"contra bool not null default false",
+		"cash_flow_category " + types.key_text + " not null default ''",
+		"currency " + types.key_text + " not null default ''",
+		"tax_category " + types.key_text + " not null default ''",
+		"allow_negative bool not null default false",
+	} {
+		_, err := tx.exec("alter table accounts add column " + column)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the entries that are posted before have a header for every simple entry they were divided to
+func migrate_entry_headers(tx sql_storage, types column_types, date_layout []string) error {
+	queries := []string{
+		"create table entries (entry_number integer not null primary key,date " + types.datetime + " not null,description text not null,name " + types.key_text + " not null,employee_name " + types.key_text + " not null,entry_date " + types.datetime + " not null)",
+		"insert into entries(entry_number,date,description,name,employee_name,entry_date) select entry_number,min(date),coalesce(min(description),''),coalesce(min(name),''),coalesce(min(employee_name),''),coalesce(min(entry_date),min(date)) from journal group by entry_number",
+	}
+	for _, query := range queries {
+		_, err := tx.exec(query)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrate_recurring_entries(tx sql_storage, types column_types, date_layout []string) error {
+	queries := []string{
+		"create table recurring_entries (title " + types.key_text + " not null primary key,frequency " + types.key_text + " not null,day_of_month integer not null,start_date " + types.datetime + " not null,end_date " + types.datetime + " null,next_date " + types.datetime + " not null,description text not null,name " + types.key_text + " not null,employee_name " + types.key_text + " not null)",
+		"create table recurring_entry_lines (id " + types.id + ",title " + types.key_text + " not null,account " + types.key_text + " not null,value " + types.decimal + ",quantity " + types.decimal + ",barcode " + types.key_text + " not null)",
+		"create table recurring_runs (id " + types.id + ",title " + types.key_text + " not null,date " + types.datetime + " not null,entry_number integer not null,run_date " + types.datetime + " not null)",
+	}
+	for _, query := range queries {
+		_, err := tx.exec(query)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrate_drafts(tx sql_storage, types column_types, date_layout []string) error {
+	queries := []string{
+		"create table drafts (draft_number integer not null primary key,auto_completion bool not null,date " + types.datetime + " not null,entry_expair " + types.datetime + " null,adjusting_method " + types.key_text + " not null,description text not null,name " + types.key_text + " not null,employee_name " + types.key_text + " not null,entry_date " + types.datetime + " not null,status " + types.key_text + " not null,reviewer_name " + types.key_text + " not null,review_date " + types.datetime + " null,comment text not null,entry_number integer not null)",
+		"create table draft_lines (id " + types.id + ",draft_number integer not null,account " + types.key_text + " not null,value " + types.decimal + ",quantity " + types.decimal + ",barcode " + types.key_text + " not null)",
+		"create table draft_days (id " + types.id + ",draft_number integer not null,day " + types.key_text + " not null,start_hour integer not null,start_minute integer not null,end_hour integer not null,end_minute integer not null)",
+	}
+	for _, query := range queries {
+		_, err := tx.exec(query)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrate_periods(tx sql_storage, types column_types, date_layout []string) error {
+	queries := []string{
+		"create table periods (id " + types.id + ",start_date " + types.datetime + " not null,end_date " + types.datetime + " not null,status " + types.key_text + " not null)",
+		"create table period_changes (id " + types.id + ",date " + types.datetime + " not null,operation " + types.key_text + " not null,start_date " + types.datetime + " not null,end_date " + types.datetime + " not null,reason text not null,employee_name " + types.key_text + " not null)",
+	}
+	for _, query := range queries {
+		_, err := tx.exec(query)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrate_year_closings(tx sql_storage, types column_types, date_layout []string) error {
+	queries := []string{
+		"create table year_closings (id integer not null primary key,end_date " + types.datetime + " not null,date " + types.datetime + " not null,employee_name " + types.key_text + " not null,undone_by " + types.key_text + " not null,undo_date " + types.datetime + " null)",
+		"create table year_closing_entries (id " + types.id + ",closing_id integer not null,entry_number integer not null)",
+	}
+	for _, query := range queries {
+		_, err := tx.exec(query)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the lines that are posted before are in the currency of the company so their currency is empty
+func migrate_currencies(tx sql_storage, types column_types, date_layout []string) error {
+	queries := []string{
+		"alter table journal add column currency " + types.key_text + " not null default ''",
+		"alter table journal add column foreign_value " + types.decimal + " not null default 0",
+		"alter table journal add column rate " + types.decimal + " not null default 0",
+		"create table exchange_rates (id " + types.id + ",currency " + types.key_text + " not null,date " + types.datetime + " not null,rate " + types.decimal + ")",
+	}
+	for _, query := range queries {
+		_, err := tx.exec(query)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parse_text_date reads the dates that time.Time.String() wrote, the layouts of the company are tried first
+// then the layout of time.Time.String() itself after removing the monotonic clock reading
+func parse_text_date(text string, date_layout []string) (time.Time, error) {
+	if text == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range date_layout {
+		date, err := time.Parse(layout, text)
+		if err == nil {
+			return date, nil
+		}
+	}
+	if index := strings.Index(text, " m="); index != -1 {
+		text = text[:index]
+	}
+	date, err := time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", text)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("can't parse the date %q with %v", text, date_layout)
+	}
+	return date, nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// the closed periods take the entries of period_managers only like the closing and the adjusting entries of the year,
+// the locked periods take nothing. every entry, reversing and change of the journal is checked against them and only
+// period_managers can close, lock and reopen them, every change is saved in period_changes
+
+type period struct {
+	start_date, end_date time.Time // start_date<=date<end_date like journal_between_dates
+	status               string    // closed or locked
+}
+
+type period_change struct {
+	id                    int
+	date                  time.Time
+	operation             string // close, lock or reopen
+	start_date, end_date  time.Time
+	reason, employee_name string
+}
+
+func (s Financial_accounting) close_period(start_date, end_date time.Time, employee_name string) error {
+	return s.change_period("close", period{start_date, end_date, "closed"}, employee_name, "")
+}
+
+// lock_period locks the period or the closed period that has the same dates
+func (s Financial_accounting) lock_period(start_date, end_date time.Time, employee_name string) error {
+	return s.change_period("lock", period{start_date, end_date, "locked"}, employee_name, "")
+}
+
+// reopen_period opens the closed or the locked period that has the same dates, the reason is saved with the change
+func (s Financial_accounting) reopen_period(start_date, end_date time.Time, employee_name, reason string) error {
+	if reason == "" {
+		return ErrInvalidSchedule{"the reason of reopening the period can't be empty"}
+	}
+	return s.change_period("reopen", period{start_date, end_date, ""}, employee_name, reason)
+}
+
+func (s Financial_accounting) periods() ([]period, error) {
+	return s.db.periods()
+}
+
+func (s Financial_accounting) period_changes() ([]period_change, error) {
+	return s.db.period_changes()
+}
+
+func (s Financial_accounting) change_period(operation string, new_period period, employee_name, reason string) error {
+	if !IS_IN(employee_name, s.period_managers) {
+		return ErrNotAuthorized{employee_name, operation + " the period"}
+	}
+	if !new_period.start_date.Before(new_period.end_date) {
+		return ErrInvalidPeriod{new_period.start_date, new_period.end_date}
+	}
+	return s.db.transaction(func(tx storage) error {
+		err := tx.lock_ledger()
+		if err != nil {
+			return err
+		}
+		periods, err := tx.periods()
+		if err != nil {
+			return err
+		}
+		var found bool
+		for _, p := range periods {
+			switch {
+			case p.start_date.Equal(new_period.start_date) && p.end_date.Equal(new_period.end_date):
+				if p.status == new_period.status || p.status == "locked" && new_period.status == "closed" {
+					return ErrInvalidSchedule{fmt.Sprint("the period from ", p.start_date, " to ", p.end_date, " is already ", p.status)}
+				}
+				found = true
+			case p.start_date.Before(new_period.end_date) && new_period.start_date.Before(p.end_date):
+				return ErrInvalidSchedule{fmt.Sprint("the period from ", new_period.start_date, " to ", new_period.end_date, " is in the ", p.status, " period from ", p.start_date, " to ", p.end_date)}
+			}
+		}
+		if operation == "reopen" && !found {
+			return ErrInvalidSchedule{fmt.Sprint("the period from ", new_period.start_date, " to ", new_period.end_date, " is not closed or locked")}
+		}
+		if found {
+			err = tx.delete_period(new_period.start_date, new_period.end_date)
+			if err != nil {
+				return err
+			}
+		}
+		if operation != "reopen" {
+			err = tx.insert_period(new_period)
+			if err != nil {
+				return err
+			}
+		}
+		return tx.insert_period_change(period_change{0, Now, operation, new_period.start_date, new_period.end_date, reason, employee_name})
+	})
+}
+
+// check_periods returns an error if one of the lines is in a locked period, or in a closed period and employee_name is not
+// in period_managers. the lines are the lines that are posted, reversed or changed
+func (s Financial_accounting) check_periods(tx storage, lines []journal_tag, employee_name string) error {
+	periods, err := tx.periods()
+	if err != nil || len(periods) == 0 {
+		return err
+	}
+	for _, line := range lines {
+		for _, p := range periods {
+			if line.date.Before(p.start_date) || !line.date.Before(p.end_date) {
+				continue
+			}
+			if p.status == "locked" || !IS_IN(employee_name, s.period_managers) {
+				return ErrClosedPeriod{line.date, p.start_date, p.end_date, p.status}
+			}
+		}
+	}
+	return nil
+}
+
+// check_account_periods checks the lines of the account on or after start_date before they are moved to another account
+func (s Financial_accounting) check_account_periods(tx storage, account string, start_date time.Time, employee_name string) error {
+	journal, err := tx.journal_of_account(account)
+	if err != nil {
+		return err
+	}
+	var lines []journal_tag
+	for _, tag := range journal {
+		if !tag.date.Before(start_date) {
+			lines = append(lines, tag)
+		}
+	}
+	return s.check_periods(tx, lines, employee_name)
+}
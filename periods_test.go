@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func post_by(s Financial_accounting, employee_name string, date time.Time, lines ...Account_value_quantity_barcode) error {
+	_, err := s.journal_entry(lines, true, false, date, time.Time{}, "", "", "", employee_name, nil)
+	return err
+}
+
+func expect_closed_period(t *testing.T, err error, status string) {
+	t.Helper()
+	if e, ok := err.(ErrClosedPeriod); !ok || e.Status != status {
+		t.Errorf("the error should be ErrClosedPeriod of the %s period not %v", status, err)
+	}
+}
+
+// the closed period takes the entries of the period managers only and the locked period takes nothing
+func TestClosedAndLockedPeriods(t *testing.T) {
+	for _, driver := range []string{"memory", "sqlite3"} {
+		s := test_company(t, driver, "")
+		s.period_managers = []string{"boss"}
+		s.initialize()
+		entry := post(t, s, test_day, line("cash", 1000, 1000), line("capital", 1000, 1000))
+		start, end := test_day, test_day.AddDate(0, 1, 0)
+		if _, ok := s.close_period(start, end, "clerk").(ErrNotAuthorized); !ok {
+			t.Errorf("%s: the clerk is not a period manager and can't close the period", driver)
+		}
+		check(t, s.close_period(start, end, "boss"))
+		if _, ok := s.close_period(start.AddDate(0, 0, 10), end.AddDate(0, 0, 10), "boss").(ErrInvalidSchedule); !ok {
+			t.Errorf("%s: the periods can't overlap", driver)
+		}
+
+		expect_closed_period(t, post_by(s, "clerk", test_day.AddDate(0, 0, 5), line("rent", 10, 10), line("cash", -10, -10)), "closed")
+		expect_closed_period(t, s.reverse_entry(uint(entry[0].entry_number), "clerk"), "closed")
+		expect_balance(t, s, "cash", 1000)
+		check(t, post_by(s, "boss", test_day.AddDate(0, 0, 5), line("rent", 10, 10), line("cash", -10, -10)))
+		// the end date is not in the period
+		check(t, post_by(s, "clerk", end, line("rent", 20, 20), line("cash", -20, -20)))
+		expect_balance(t, s, "rent", 30)
+
+		check(t, s.lock_period(start, end, "boss"))
+		expect_closed_period(t, post_by(s, "boss", test_day.AddDate(0, 0, 5), line("rent", 10, 10), line("cash", -10, -10)), "locked")
+		expect_closed_period(t, post_by(s, "clerk", test_day.AddDate(0, 0, 5), line("rent", 10, 10), line("cash", -10, -10)), "locked")
+		if _, ok := s.close_period(start, end, "boss").(ErrInvalidSchedule); !ok {
+			t.Errorf("%s: the locked period can't be closed", driver)
+		}
+		expect_balance(t, s, "rent", 30)
+
+		if err := s.reopen_period(start, end, "boss", ""); err == nil {
+			t.Errorf("%s: the period is reopened without a reason", driver)
+		}
+		check(t, s.reopen_period(start, end, "boss", "the rent of the month is missing"))
+		check(t, post_by(s, "clerk", test_day.AddDate(0, 0, 5), line("rent", 10, 10), line("cash", -10, -10)))
+		expect_balance(t, s, "rent", 40)
+
+		changes, err := s.period_changes()
+		check(t, err)
+		if len(changes) != 3 || changes[0].operation != "close" || changes[1].operation != "lock" || changes[2].operation != "reopen" ||
+			changes[2].reason != "the rent of the month is missing" || changes[2].employee_name != "boss" {
+			t.Errorf("%s: the changes of the periods are %v", driver, changes)
+		}
+		periods, err := s.periods()
+		check(t, err)
+		if len(periods) != 0 {
+			t.Errorf("%s: the reopened period is still there %v", driver, periods)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// a recurring entry is posted by post_recurring_entries on every date it is due through journal_entry, the dates that
+// are missed are posted the next time it runs so nothing is skipped, and every posting is saved in recurring_runs.
+// the approval rules are checked when it is created and on every posting so a rule that is added later stops it too
+
+type recurring_entry struct {
+	title                            string // the key of the recurring entry like rent
+	lines                            []Account_value_quantity_barcode
+	frequency                        string    // daily, weekly, monthly or yearly
+	day_of_month                     int       // for monthly and yearly, the last day of the month is used if the month is shorter
+	start_date, end_date, next_date  time.Time // the zero end_date means it has no end
+	description, name, employee_name string
+}
+
+type recurring_run struct {
+	title        string
+	date         time.Time // the date that the entry was due, the entry is posted on it
+	entry_number int
+	run_date     time.Time
+}
+
+// create_recurring_entry checks the lines like journal_entry does with the approval rules and saves the entry to be posted from start_date,
+// day_of_month is the day of start_date if it is 0
+func (s Financial_accounting) create_recurring_entry(r recurring_entry) error {
+	if r.title == "" {
+		return ErrInvalidSchedule{"the title of the recurring entry can't be empty"}
+	}
+	if !IS_IN(r.frequency, recurring_frequencies[:]) {
+		return ErrInvalidSchedule{fmt.Sprint("the frequency ", r.frequency, " of ", r.title, " is not in ", recurring_frequencies)}
+	}
+	switch {
+	case r.frequency == "daily" || r.frequency == "weekly":
+		if r.day_of_month != 0 {
+			return ErrInvalidSchedule{fmt.Sprint("day_of_month is for the monthly and yearly entries only and ", r.title, " is ", r.frequency)}
+		}
+	case r.day_of_month == 0:
+		r.day_of_month = r.start_date.Day()
+	case r.day_of_month < 0 || r.day_of_month > 31:
+		return ErrInvalidSchedule{fmt.Sprint("day_of_month ", r.day_of_month, " of ", r.title, " should be from 1 to 31")}
+	}
+	if r.start_date.IsZero() {
+		return ErrInvalidSchedule{fmt.Sprint("the start_date of ", r.title, " is empty")}
+	}
+	if !r.end_date.IsZero() && r.end_date.Before(r.start_date) {
+		return ErrInvalidPeriod{r.start_date, r.end_date}
+	}
+	lines := append([]Account_value_quantity_barcode{}, r.lines...)
+	s.account_of_code(lines)
+	s.round_entry(lines)
+	lines = remove_zero_values(group_by_account_and_barcode(lines))
+	err := s.check_known_accounts(lines)
+	if err != nil {
+		return err
+	}
+	_, _, err = s.check_debit_equal_credit(lines, false)
+	if err != nil {
+		return err
+	}
+	err = s.check_approval_rules(lines)
+	if err != nil {
+		return err
+	}
+	r.lines = lines
+	r.next_date = first_recurring_date(r)
+	return s.db.transaction(func(tx storage) error {
+		recurring, err := tx.recurring_entries()
+		if err != nil {
+			return err
+		}
+		for _, a := range recurring {
+			if a.title == r.title {
+				return ErrInvalidSchedule{fmt.Sprint("the recurring entry ", r.title, " is already there")}
+			}
+		}
+		return tx.insert_recurring_entry(r)
+	})
+}
+
+func (s Financial_accounting) delete_recurring_entry(title string) error {
+	return s.db.delete_recurring_entry(title)
+}
+
+func (s Financial_accounting) recurring_entries() ([]recurring_entry, error) {
+	return s.db.recurring_entries()
+}
+
+func (s Financial_accounting) recurring_runs() ([]recurring_run, error) {
+	return s.db.recurring_runs()
+}
+
+// post_recurring_entries posts every recurring entry on each of its dates that are on or before until and not posted yet.
+// every date is posted in its own transaction, if one fails the dates before it stay posted and the error is returned
+func (s Financial_accounting) post_recurring_entries(until time.Time) ([]recurring_run, error) {
+	recurring, err := s.db.recurring_entries()
+	if err != nil {
+		return nil, err
+	}
+	var runs []recurring_run
+	for _, a := range recurring {
+		for {
+			var run recurring_run
+			var posted bool
+			err = s.db.transaction(func(tx storage) error {
+				err := tx.lock_ledger()
+				if err != nil {
+					return err
+				}
+				// the date is read again so two runs at the same time don't post it twice
+				recurring, err := tx.recurring_entries()
+				if err != nil {
+					return err
+				}
+				for _, r := range recurring {
+					if r.title != a.title || !is_recurring_due(r, until) {
+						continue
+					}
+					poster := s
+					poster.db = tx
+					entry, err := poster.post_entry(r.lines, true, false, r.next_date, time.Time{}, "", r.description, r.name, r.employee_name, nil, true)
+					if err != nil {
+						return err
+					}
+					run = recurring_run{r.title, r.next_date, entry[0].entry_number, Now}
+					err = tx.insert_recurring_run(run)
+					if err != nil {
+						return err
+					}
+					posted = true
+					return tx.set_recurring_next_date(r.title, next_recurring_date(r))
+				}
+				return nil
+			})
+			if err != nil {
+				return runs, err
+			}
+			if !posted {
+				break
+			}
+			runs = append(runs, run)
+		}
+	}
+	return runs, nil
+}
+
+func is_recurring_due(r recurring_entry, until time.Time) bool {
+	return !r.next_date.After(until) && (r.end_date.IsZero() || !r.next_date.After(r.end_date))
+}
+
+// first_recurring_date is start_date or the first day_of_month after it
+func first_recurring_date(r recurring_entry) time.Time {
+	start := r.start_date
+	var date time.Time
+	switch r.frequency {
+	case "monthly":
+		date = date_in_month(start.Year(), start.Month(), r.day_of_month, start)
+		if date.Before(start) {
+			date = date_in_month(start.Year(), start.Month()+1, r.day_of_month, start)
+		}
+	case "yearly":
+		date = date_in_month(start.Year(), start.Month(), r.day_of_month, start)
+		if date.Before(start) {
+			date = date_in_month(start.Year()+1, start.Month(), r.day_of_month, start)
+		}
+	default:
+		date = start
+	}
+	return date
+}
+
+// next_recurring_date returns the date after next_date, the monthly and yearly entries go back to day_of_month after the shorter months
+func next_recurring_date(r recurring_entry) time.Time {
+	date := r.next_date
+	switch r.frequency {
+	case "daily":
+		return date.AddDate(0, 0, 1)
+	case "weekly":
+		return date.AddDate(0, 0, 7)
+	case "monthly":
+		return date_in_month(date.Year(), date.Month()+1, r.day_of_month, date)
+	}
+	return date_in_month(date.Year()+1, date.Month(), r.day_of_month, date)
+}
+
+// date_in_month returns the day in the month at the clock of clock, or the last day of the month if it is shorter
+func date_in_month(year int, month time.Month, day int, clock time.Time) time.Time {
+	first := time.Date(year, month, 1, clock.Hour(), clock.Minute(), clock.Second(), clock.Nanosecond(), clock.Location())
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
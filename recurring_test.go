@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func test_date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// the dates that are missed are posted once each in order of the titles and the dates the next time it runs and the run after it posts nothing,
+// the monthly entry on the 31st is on the last day of the shorter months and goes back to the 31st after them
+func TestRecurringCatchUp(t *testing.T) {
+	for _, driver := range []string{"memory", "sqlite3"} {
+		s := test_company(t, driver, "")
+		s.initialize()
+		post(t, s, test_day, line("cash", 1000, 1000), line("capital", 1000, 1000))
+		check(t, s.create_recurring_entry(recurring_entry{"rent", []Account_value_quantity_barcode{line("rent", 60, 60), line("cash", -60, -60)}, "monthly", 31, test_day, time.Time{}, time.Time{}, "", "", "clerk"}))
+		check(t, s.create_recurring_entry(recurring_entry{"interest", []Account_value_quantity_barcode{line("interest_expense", 5, 5), line("cash", -5, -5)}, "weekly", 0, test_day, test_day.AddDate(0, 0, 20), time.Time{}, "", "", "clerk"}))
+
+		runs, err := s.post_recurring_entries(test_date(2021, 4, 15))
+		check(t, err)
+		expected := []recurring_run{
+			{"interest", test_date(2021, 1, 1), 0, time.Time{}},
+			{"interest", test_date(2021, 1, 8), 0, time.Time{}},
+			{"interest", test_date(2021, 1, 15), 0, time.Time{}},
+			{"rent", test_date(2021, 1, 31), 0, time.Time{}},
+			{"rent", test_date(2021, 2, 28), 0, time.Time{}},
+			{"rent", test_date(2021, 3, 31), 0, time.Time{}},
+		}
+		if len(runs) != len(expected) {
+			t.Fatalf("%s: the runs are %v", driver, runs)
+		}
+		for index, run := range runs {
+			if run.title != expected[index].title || !run.date.Equal(expected[index].date) {
+				t.Errorf("%s: the run %d is %s on %s not %s on %s", driver, index, run.title, run.date, expected[index].title, expected[index].date)
+			}
+			entry, err := s.db.journal_of_entry_number(uint(run.entry_number))
+			check(t, err)
+			if len(entry) != 2 || !entry[0].date.Equal(run.date) {
+				t.Errorf("%s: the entry of the run %v is %v", driver, run, entry)
+			}
+		}
+		expect_balance(t, s, "rent", 180)
+		expect_balance(t, s, "interest_expense", 15)
+		recurring, err := s.recurring_entries()
+		check(t, err)
+		for _, r := range recurring {
+			if r.title == "rent" && !r.next_date.Equal(test_date(2021, 4, 30)) || r.title == "interest" && !r.next_date.Equal(test_date(2021, 1, 22)) {
+				t.Errorf("%s: the next date of %s is %s", driver, r.title, r.next_date)
+			}
+		}
+
+		runs, err = s.post_recurring_entries(test_date(2021, 4, 15))
+		check(t, err)
+		if len(runs) != 0 {
+			t.Errorf("%s: the second run posted %v", driver, runs)
+		}
+		runs, err = s.post_recurring_entries(test_date(2021, 5, 31))
+		check(t, err)
+		if len(runs) != 2 || !runs[0].date.Equal(test_date(2021, 4, 30)) || !runs[1].date.Equal(test_date(2021, 5, 31)) {
+			t.Errorf("%s: the rent of april and may is %v", driver, runs)
+		}
+		all_runs, err := s.recurring_runs()
+		check(t, err)
+		if len(all_runs) != 8 {
+			t.Errorf("%s: the recurring runs are %v", driver, all_runs)
+		}
+		expect_balance(t, s, "rent", 300)
+		expect_balance(t, s, "interest_expense", 15)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// balance_snapshot is the sum of the entries before date for one account and name, so the statements start
+// from it and read only the entries after it. the rows with account_flow are only the keys that the flows made
+// before the date, the flows themselves are counted after start_date only so they are zero in the snapshot.
+// the rows don't depend on the fathers of the accounts, so changing them don't make the rows wrong. the keys of the flows
+// depend on is_credit only and it can't change for the accounts that have entries
+type balance_snapshot struct {
+	date                        time.Time
+	account_flow, account, name string
+	value, quantity             decimal.Decimal
+}
+
+// the snapshots are taken at the start of the months
+func snapshot_date(date time.Time) time.Time {
+	year, month, _ := date.UTC().Date()
+	return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+type snapshot_key struct {
+	account_flow, account, name string
+}
+
+// snapshot_sums keeps the order that the keys came in so the snapshots are saved in the same order every time
+type snapshot_sums struct {
+	sums map[snapshot_key]*balance_snapshot
+	keys []snapshot_key
+}
+
+func (s *snapshot_sums) add(account_flow, account, name string, value, quantity decimal.Decimal) {
+	if s.sums == nil {
+		s.sums = map[snapshot_key]*balance_snapshot{}
+	}
+	k := snapshot_key{account_flow, account, name}
+	row := s.sums[k]
+	if row == nil {
+		row = &balance_snapshot{account_flow: account_flow, account: account, name: name}
+		s.sums[k] = row
+		s.keys = append(s.keys, k)
+	}
+	row.value = row.value.Add(value)
+	row.quantity = row.quantity.Add(quantity)
+}
+
+func (s *snapshot_sums) rows() []balance_snapshot {
+	rows := make([]balance_snapshot, 0, len(s.keys))
+	for _, k := range s.keys {
+		rows = append(rows, *s.sums[k])
+	}
+	return rows
+}
+
+// snapshot_rows sums the lines of the journal as they will be in the snapshot, the journal should be ordered by date and entry_number
+func (s Financial_accounting) snapshot_rows(journal []journal_tag) ([]balance_snapshot, error) {
+	var sums snapshot_sums
+	for _, one_entry := range group_by_entry_number(journal) {
+		for _, a := range one_entry {
+			sums.add("", a.account, a.name, a.value, a.quantity)
+		}
+		flows, err := s.entry_flows(one_entry)
+		if err != nil {
+			return nil, err
+		}
+		for _, flow := range flows {
+			sums.add(flow[0].account, flow[1].account, flow[1].name, decimal.Zero, decimal.Zero)
+		}
+	}
+	return sums.rows(), nil
+}
+
+func merge_snapshot_rows(snapshots ...[]balance_snapshot) []balance_snapshot {
+	var sums snapshot_sums
+	for _, snapshot := range snapshots {
+		for _, row := range snapshot {
+			sums.add(row.account_flow, row.account, row.name, row.value, row.quantity)
+		}
+	}
+	return sums.rows()
+}
+
+// group_by_entry_number splits the adjusted entries by their dates too, every date of them is counted alone
+func group_by_entry_number(journal []journal_tag) [][]journal_tag {
+	var groups [][]journal_tag
+	for index, entry := range journal {
+		if index == 0 || journal[index-1].entry_number != entry.entry_number || !journal[index-1].date.Equal(entry.date) {
+			groups = append(groups, []journal_tag{})
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], entry)
+	}
+	return groups
+}
+
+// statement_snapshot returns the snapshot at the start of the month of start_date and the entries from it to end_date,
+// they are read in one read only transaction so the postings don't wait for the statement
+func (s Financial_accounting) statement_snapshot(start_date, end_date time.Time) ([]balance_snapshot, []journal_tag, error) {
+	date := snapshot_date(start_date)
+	var snapshot []balance_snapshot
+	var journal []journal_tag
+	var found bool
+	err := s.db.read_only(func(tx storage) error {
+		dates, err := tx.snapshot_dates()
+		if err != nil {
+			return err
+		}
+		for _, a := range dates {
+			if a.Equal(date) {
+				found = true
+			}
+		}
+		if !found {
+			return nil
+		}
+		snapshot, err = tx.snapshot(date)
+		if err != nil {
+			return err
+		}
+		journal, err = tx.journal_between_dates(date, end_date)
+		return err
+	})
+	if err != nil || found {
+		return snapshot, journal, err
+	}
+	err = s.db.transaction(func(tx storage) error { return s.make_snapshot(tx, date) })
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.statement_snapshot(start_date, end_date)
+}
+
+// make_snapshot saves the snapshot of date from the last one before it. it is the only part of the statements that locks the ledger,
+// so two statements can't make the same snapshot and an entry before date that is posted while it is made is either read
+// here or added to it by update_snapshots. it runs once for every month
+func (s Financial_accounting) make_snapshot(tx storage, date time.Time) error {
+	err := tx.lock_ledger()
+	if err != nil {
+		return err
+	}
+	dates, err := tx.snapshot_dates()
+	if err != nil {
+		return err
+	}
+	var previous time.Time
+	for _, a := range dates {
+		if a.Equal(date) {
+			return nil
+		}
+		if a.Before(date) && a.After(previous) {
+			previous = a
+		}
+	}
+	var previous_snapshot []balance_snapshot
+	if !previous.IsZero() {
+		previous_snapshot, err = tx.snapshot(previous)
+		if err != nil {
+			return err
+		}
+	}
+	journal, err := tx.journal_between_dates(previous, date)
+	if err != nil {
+		return err
+	}
+	rows, err := s.snapshot_rows(journal)
+	if err != nil {
+		return err
+	}
+	return tx.insert_snapshot(date, merge_snapshot_rows(previous_snapshot, rows))
+}
+
+// update_snapshots adds the entries that are posted to the snapshots after them
+func (s Financial_accounting) update_snapshots(tx storage, journal []journal_tag) error {
+	dates, err := tx.snapshot_dates()
+	if err != nil || len(dates) == 0 {
+		return err
+	}
+	var last time.Time
+	for _, a := range dates {
+		if a.After(last) {
+			last = a
+		}
+	}
+	for _, one_entry := range group_by_entry_number(journal) {
+		date := one_entry[0].date
+		if !date.Before(last) {
+			continue
+		}
+		rows, err := s.snapshot_rows(one_entry)
+		if err != nil {
+			return err
+		}
+		err = tx.add_to_snapshots(date, rows)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sum_snapshot puts the snapshot in the statement as if its entries were read from the journal before start_date
+func (s Financial_accounting) sum_snapshot(snapshot []balance_snapshot, start_date time.Time, flow_statement map[string]map[string]map[string]map[string]map[string]decimal.Decimal, nan_flow_statement map[string]map[string]map[string]map[string]decimal.Decimal) error {
+	for _, row := range snapshot {
+		if row.account_flow == "" {
+			err := s.sum_values(time.Time{}, start_date, []journal_tag{{account: row.account, name: row.name, value: row.value, quantity: row.quantity}}, nan_flow_statement)
+			if err != nil {
+				return err
+			}
+		} else {
+			initialize_map_4(flow_statement, row.account_flow, row.account, row.name, "value")
+			initialize_map_4(flow_statement, row.account_flow, row.account, row.name, "quantity")
+		}
+	}
+	return nil
+}
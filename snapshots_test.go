@@ -0,0 +1,64 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// the statements that need the same missing snapshot make it once
+func TestConcurrentSnapshots(t *testing.T) {
+	s := test_company(t, "sqlite3", "")
+	s.initialize()
+	post(t, s, test_day, line("cash", 1000, 1000), line("capital", 1000, 1000))
+	post(t, s, test_day.AddDate(0, 2, 0), line("rent", 10, 10), line("cash", -10, -10))
+	var wait sync.WaitGroup
+	errors := make([]error, 4)
+	for index := range errors {
+		wait.Add(1)
+		go func(index int) {
+			defer wait.Done()
+			_, _, _, errors[index] = s.financial_statements(test_day.AddDate(0, 2, 0), test_day.AddDate(0, 3, 0), 1, nil, false)
+		}(index)
+	}
+	wait.Wait()
+	for _, err := range errors {
+		check(t, err)
+	}
+	dates, err := s.db.snapshot_dates()
+	check(t, err)
+	seen := map[string]bool{}
+	for _, date := range dates {
+		if seen[date.String()] {
+			t.Errorf("the snapshot of %s is made twice", date)
+		}
+		seen[date.String()] = true
+	}
+}
+
+// the statement of a month that has its snapshot reads without waiting for the posting that holds the ledger
+func TestStatementDoesNotWaitForPosting(t *testing.T) {
+	s := test_company(t, "sqlite3", "")
+	s.initialize()
+	post(t, s, test_day, line("cash", 1000, 1000), line("capital", 1000, 1000))
+	_, _, _, err := s.financial_statements(test_day, test_day.AddDate(0, 1, 0), 1, nil, false)
+	check(t, err)
+	check(t, s.db.transaction(func(tx storage) error {
+		err := tx.lock_ledger()
+		if err != nil {
+			return err
+		}
+		done := make(chan error, 1)
+		go func() {
+			_, _, _, err := s.financial_statements(test_day, test_day.AddDate(0, 1, 0), 1, nil, false)
+			done <- err
+		}()
+		select {
+		case err = <-done:
+			return err
+		case <-time.After(5 * time.Second):
+			t.Error("the statement waits for the transaction that locked the ledger")
+			return nil
+		}
+	}))
+}
@@ -0,0 +1,901 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// storage is everything the ledger needs from the journal and inventory tables
+type storage interface {
+	transaction(f func(tx storage) error) error
+	read_only(f func(tx storage) error) error // f reads one state of the database without waiting for the postings
+	migrate(date_layout []string) error
+	journal() ([]journal_tag, error)
+	journal_of_entry_number(entry_number uint) ([]journal_tag, error)
+	journal_of_entry_number_between(entry_number uint, start_date, end_date time.Time) ([]journal_tag, error)
+	journal_of_account_between(account string, start_date, end_date time.Time) ([]journal_tag, error)
+	journal_between_dates(start_date, end_date time.Time) ([]journal_tag, error) // start_date<=date<end_date
+	insert_journal(tag journal_tag) error
+	header_of_entry_number(entry_number uint) (entry_header, error) // sql.ErrNoRows if the entry has no header
+	insert_entry_header(header entry_header) error
+	delete_entry_header(entry_number int) error
+	set_reverse(id int) error
+	delete_journal(id int) error
+	lock_ledger() error
+	next_entry_numbers(count int) (int, error)
+	account_balance(account string, before_date time.Time) (decimal.Decimal, error)
+	account_of_barcode(barcode string) (string, error)
+	is_account_used(account string) (bool, error)
+	rename_account(name, new_name string) error
+	accounts() ([]account, error)
+	insert_account(a account) error
+	update_account(name string, a account) error // the sons of name take the new name too
+	delete_account(name string) error
+	journal_of_account(account string) ([]journal_tag, error)
+	move_journal(account, new_account string, start_date time.Time) error // the entries on or after start_date
+	account_changes() ([]account_change, error)
+	insert_account_change(change account_change) error
+	recurring_entries() ([]recurring_entry, error)
+	insert_recurring_entry(r recurring_entry) error
+	set_recurring_next_date(title string, next_date time.Time) error
+	delete_recurring_entry(title string) error
+	recurring_runs() ([]recurring_run, error)
+	insert_recurring_run(run recurring_run) error
+	drafts() ([]draft, error)
+	insert_draft(d draft) error
+	set_draft_review(draft_number int, status, reviewer_name string, review_date time.Time, comment string, entry_number int) error
+	periods() ([]period, error)
+	insert_period(p period) error
+	delete_period(start_date, end_date time.Time) error
+	period_changes() ([]period_change, error)
+	insert_period_change(change period_change) error
+	year_closings() ([]year_closing, error)
+	insert_year_closing(closing year_closing) error
+	set_year_closing_undone(id int, employee_name string, date time.Time) error
+	exchange_rates(currency string) ([]exchange_rate, error)
+	insert_exchange_rate(rate exchange_rate) error
+	delete_exchange_rate(currency string, date time.Time) error
+	inventory_accounts() ([]string, error)
+	inventory_layers(account, barcode, order_by_date_asc_or_desc string) ([]journal_tag, error)
+	inventory_layer_of_line(tag journal_tag) (journal_tag, error) // sql.ErrNoRows if the line made no layer
+	insert_inventory(tag journal_tag) error
+	set_inventory_layer_quantity(id int, quantity decimal.Decimal) error
+	delete_inventory_layer(id int) error
+	delete_empty_inventory() error
+	weighted_average(account string) error
+	snapshot_dates() ([]time.Time, error)
+	snapshot(date time.Time) ([]balance_snapshot, error)
+	insert_snapshot(date time.Time, rows []balance_snapshot) error
+	add_to_snapshots(after time.Time, rows []balance_snapshot) error
+	delete_snapshots(after time.Time) error
+}
+
+const journal_columns = "id,date,entry_number,account,value,price,quantity,barcode,entry_expair,description,name,employee_name,entry_date,reverse,currency,foreign_value,rate"
+
+// executor is satisfied by both *sql.DB and *sql.Tx
+type executor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// sql_storage runs the same queries on every sql database, the dialect rewrites what differs
+type sql_storage struct {
+	db      executor
+	pool    *sql.DB // nil when db is already a transaction
+	reader  *sql.DB // the pool of the read only transactions
+	dialect dialect
+}
+
+func open_storage(driver_name, data_source_name, database_name string) (storage, error) {
+	var d dialect
+	switch driver_name {
+	case "mysql":
+		d = mysql_dialect{}
+	case "sqlite3":
+		d = sqlite_dialect{}
+	case "postgres":
+		d = postgres_dialect{}
+	case "memory":
+		return open_memory(), nil
+	default:
+		return nil, fmt.Errorf("%s is not in [mysql,sqlite3,postgres,memory]", driver_name)
+	}
+	db, err := d.open(data_source_name, database_name)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := d.open_reader(db, data_source_name, database_name)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return sql_storage{db, db, reader, d}, nil
+}
+
+func (s sql_storage) exec(query string, args ...interface{}) (sql.Result, error) {
+	return s.db.Exec(s.dialect.rebind(query), args...)
+}
+
+func (s sql_storage) query(query string, args ...interface{}) (*sql.Rows, error) {
+	return s.db.Query(s.dialect.rebind(query), args...)
+}
+
+func (s sql_storage) query_row(query string, args ...interface{}) *sql.Row {
+	return s.db.QueryRow(s.dialect.rebind(query), args...)
+}
+
+func (s sql_storage) transaction(f func(tx storage) error) error {
+	return s.begin(func(tx sql_storage) error { return f(tx) })
+}
+
+// begin commits when f returns nil and rolls back when f returns an error or panics.
+// calling it on a storage that is already a transaction just runs f inside it
+func (s sql_storage) begin(f func(tx sql_storage) error) error {
+	if s.pool == nil {
+		return f(s)
+	}
+	tx, err := s.pool.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+	err = f(sql_storage{tx, nil, nil, s.dialect})
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// read_only reads in a repeatable read transaction so every query of f sees the database as it was at the first one,
+// it takes no locks so the postings don't wait for it and it doesn't wait for them
+func (s sql_storage) read_only(f func(tx storage) error) error {
+	if s.pool == nil {
+		return f(s)
+	}
+	tx, err := s.reader.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	return f(sql_storage{tx, nil, nil, s.dialect})
+}
+
+func (s sql_storage) journal() ([]journal_tag, error) {
+	return scan_journal(s.query("select " + journal_columns + " from journal order by date,entry_number,id"))
+}
+
+func (s sql_storage) journal_of_entry_number(entry_number uint) ([]journal_tag, error) {
+	return scan_journal(s.query("select "+journal_columns+" from journal where entry_number=? order by date,id", entry_number))
+}
+
+func (s sql_storage) journal_of_entry_number_between(entry_number uint, start_date, end_date time.Time) ([]journal_tag, error) {
+	return scan_journal(s.query("select "+journal_columns+" from journal where date>? and date<? and entry_number=? order by date,id", start_date.UTC(), end_date.UTC(), entry_number))
+}
+
+func (s sql_storage) journal_of_account_between(account string, start_date, end_date time.Time) ([]journal_tag, error) {
+	return scan_journal(s.query("select "+journal_columns+" from journal where date>? and date<? and account=? order by date,id", start_date.UTC(), end_date.UTC(), account))
+}
+
+func (s sql_storage) journal_between_dates(start_date, end_date time.Time) ([]journal_tag, error) {
+	return scan_journal(s.query("select "+journal_columns+" from journal where date>=? and date<? order by date,entry_number,id", start_date.UTC(), end_date.UTC()))
+}
+
+func (s sql_storage) insert_journal(tag journal_tag) error {
+	_, err := s.exec("insert into journal(date,entry_number,account,value,price,quantity,barcode,entry_expair,description,name,employee_name,entry_date,reverse,currency,foreign_value,rate) values (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)",
+		tag.date.UTC(), tag.entry_number, tag.account, tag.value, tag.price, tag.quantity, tag.barcode,
+		null_time(tag.entry_expair), tag.description, tag.name, tag.employee_name, tag.entry_date.UTC(), tag.reverse, tag.currency, tag.foreign_value, tag.rate)
+	return err
+}
+
+func (s sql_storage) header_of_entry_number(entry_number uint) (entry_header, error) {
+	var header entry_header
+	err := s.query_row("select entry_number,date,description,name,employee_name,entry_date from entries where entry_number=?", entry_number).Scan(
+		&header.entry_number, &header.date, &header.description, &header.name, &header.employee_name, &header.entry_date)
+	return header, err
+}
+
+func (s sql_storage) insert_entry_header(header entry_header) error {
+	_, err := s.exec("insert into entries(entry_number,date,description,name,employee_name,entry_date) values (?,?,?,?,?,?)",
+		header.entry_number, header.date.UTC(), header.description, header.name, header.employee_name, header.entry_date.UTC())
+	return err
+}
+
+func (s sql_storage) delete_entry_header(entry_number int) error {
+	_, err := s.exec("delete from entries where entry_number=?", entry_number)
+	return err
+}
+
+func (s sql_storage) set_reverse(id int) error {
+	_, err := s.exec("update journal set reverse=? where id=?", true, id)
+	return err
+}
+
+func (s sql_storage) delete_journal(id int) error {
+	_, err := s.exec("delete from journal where id=?", id)
+	return err
+}
+
+// lock_ledger updates the row of the sequence so the other transactions that post wait until this one ends,
+// then the balances and the inventory they read can't change under them
+func (s sql_storage) lock_ledger() error {
+	_, err := s.exec("update entry_number_sequence set value=value where name=?", "journal")
+	return err
+}
+
+// next_entry_numbers takes count numbers from the sequence and returns the first of them
+func (s sql_storage) next_entry_numbers(count int) (int, error) {
+	_, err := s.exec("update entry_number_sequence set value=value+? where name=?", count, "journal")
+	if err != nil {
+		return 0, err
+	}
+	var last int
+	err = s.query_row("select value from entry_number_sequence where name=?", "journal").Scan(&last)
+	return last - count + 1, err
+}
+
+// the sums are done in go because sqlite would sum the values as float
+func (s sql_storage) account_balance(account string, before_date time.Time) (decimal.Decimal, error) {
+	return sum_column(s.query("select value from journal where account=? and date<?", account, before_date.UTC()))
+}
+
+func (s sql_storage) account_of_barcode(barcode string) (string, error) {
+	var account string
+	err := s.query_row("select account from journal where barcode=? limit 1", barcode).Scan(&account)
+	return account, err
+}
+
+func (s sql_storage) is_account_used(account string) (bool, error) {
+	var tag string
+	err := s.query_row("select account from journal where account=? limit 1", account).Scan(&tag)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s sql_storage) rename_account(name, new_name string) error {
+	_, err := s.exec("update journal set account=? where account=?", new_name, name)
+	if err != nil {
+		return err
+	}
+	_, err = s.exec("update inventory set account=? where account=?", new_name, name)
+	if err != nil {
+		return err
+	}
+	_, err = s.exec("update snapshot_balance set account=? where account=?", new_name, name)
+	if err != nil {
+		return err
+	}
+	_, err = s.exec("update snapshot_balance set account_flow=? where account_flow=?", new_name, name)
+	if err != nil {
+		return err
+	}
+	_, err = s.exec("update recurring_entry_lines set account=? where account=?", new_name, name)
+	if err != nil {
+		return err
+	}
+	_, err = s.exec("update draft_lines set account=? where account=?", new_name, name)
+	return err
+}
+
+func (s sql_storage) accounts() ([]account, error) {
+	rows, err := s.query("select name,father,is_credit,cost_flow_type,inactive,code,contra,cash_flow_category,currency,tax_category,allow_negative from accounts order by id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var accounts []account
+	for rows.Next() {
+		var a account
+		err = rows.Scan(&a.name, &a.father, &a.is_credit, &a.cost_flow_type, &a.inactive, &a.code, &a.contra, &a.cash_flow_category, &a.currency, &a.tax_category, &a.allow_negative)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts, rows.Err()
+}
+
+func (s sql_storage) insert_account(a account) error {
+	_, err := s.exec("insert into accounts(name,father,is_credit,cost_flow_type,inactive,code,contra,cash_flow_category,currency,tax_category,allow_negative) values (?,?,?,?,?,?,?,?,?,?,?)",
+		a.name, a.father, a.is_credit, a.cost_flow_type, a.inactive, a.code, a.contra, a.cash_flow_category, a.currency, a.tax_category, a.allow_negative)
+	return err
+}
+
+func (s sql_storage) update_account(name string, a account) error {
+	_, err := s.exec("update accounts set name=?,father=?,is_credit=?,cost_flow_type=?,inactive=?,code=?,contra=?,cash_flow_category=?,currency=?,tax_category=?,allow_negative=? where name=?",
+		a.name, a.father, a.is_credit, a.cost_flow_type, a.inactive, a.code, a.contra, a.cash_flow_category, a.currency, a.tax_category, a.allow_negative, name)
+	if err != nil || name == a.name {
+		return err
+	}
+	_, err = s.exec("update accounts set father=? where father=?", a.name, name)
+	return err
+}
+
+func (s sql_storage) delete_account(name string) error {
+	_, err := s.exec("delete from accounts where name=?", name)
+	return err
+}
+
+func (s sql_storage) journal_of_account(account string) ([]journal_tag, error) {
+	return scan_journal(s.query("select "+journal_columns+" from journal where account=? order by date,entry_number,id", account))
+}
+
+func (s sql_storage) move_journal(account, new_account string, start_date time.Time) error {
+	_, err := s.exec("update journal set account=? where account=? and date>=?", new_account, account, start_date.UTC())
+	return err
+}
+
+func (s sql_storage) account_changes() ([]account_change, error) {
+	rows, err := s.query("select id,date,operation,account,new_account,details,employee_name from account_changes order by id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var changes []account_change
+	for rows.Next() {
+		var change account_change
+		err = rows.Scan(&change.id, &change.date, &change.operation, &change.account, &change.new_account, &change.details, &change.employee_name)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+func (s sql_storage) insert_account_change(change account_change) error {
+	_, err := s.exec("insert into account_changes(date,operation,account,new_account,details,employee_name) values (?,?,?,?,?,?)",
+		change.date.UTC(), change.operation, change.account, change.new_account, change.details, change.employee_name)
+	return err
+}
+
+func (s sql_storage) recurring_entries() ([]recurring_entry, error) {
+	rows, err := s.query("select title,frequency,day_of_month,start_date,end_date,next_date,description,name,employee_name from recurring_entries order by title")
+	if err != nil {
+		return nil, err
+	}
+	var recurring []recurring_entry
+	index_of_title := map[string]int{}
+	for rows.Next() {
+		var r recurring_entry
+		var end_date sql.NullTime
+		err = rows.Scan(&r.title, &r.frequency, &r.day_of_month, &r.start_date, &end_date, &r.next_date, &r.description, &r.name, &r.employee_name)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		r.end_date = end_date.Time
+		index_of_title[r.title] = len(recurring)
+		recurring = append(recurring, r)
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	rows, err = s.query("select title,account,value,quantity,barcode from recurring_entry_lines order by id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var title string
+		var line Account_value_quantity_barcode
+		err = rows.Scan(&title, &line.Account, &line.value, &line.quantity, &line.barcode)
+		if err != nil {
+			return nil, err
+		}
+		if index, ok := index_of_title[title]; ok {
+			recurring[index].lines = append(recurring[index].lines, line)
+		}
+	}
+	return recurring, rows.Err()
+}
+
+func (s sql_storage) insert_recurring_entry(r recurring_entry) error {
+	_, err := s.exec("insert into recurring_entries(title,frequency,day_of_month,start_date,end_date,next_date,description,name,employee_name) values (?,?,?,?,?,?,?,?,?)",
+		r.title, r.frequency, r.day_of_month, r.start_date.UTC(), null_time(r.end_date), r.next_date.UTC(), r.description, r.name, r.employee_name)
+	if err != nil {
+		return err
+	}
+	for _, line := range r.lines {
+		_, err = s.exec("insert into recurring_entry_lines(title,account,value,quantity,barcode) values (?,?,?,?,?)", r.title, line.Account, line.value, line.quantity, line.barcode)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s sql_storage) set_recurring_next_date(title string, next_date time.Time) error {
+	_, err := s.exec("update recurring_entries set next_date=? where title=?", next_date.UTC(), title)
+	return err
+}
+
+func (s sql_storage) delete_recurring_entry(title string) error {
+	_, err := s.exec("delete from recurring_entry_lines where title=?", title)
+	if err != nil {
+		return err
+	}
+	_, err = s.exec("delete from recurring_entries where title=?", title)
+	return err
+}
+
+func (s sql_storage) recurring_runs() ([]recurring_run, error) {
+	rows, err := s.query("select title,date,entry_number,run_date from recurring_runs order by id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var runs []recurring_run
+	for rows.Next() {
+		var run recurring_run
+		err = rows.Scan(&run.title, &run.date, &run.entry_number, &run.run_date)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+func (s sql_storage) insert_recurring_run(run recurring_run) error {
+	_, err := s.exec("insert into recurring_runs(title,date,entry_number,run_date) values (?,?,?,?)", run.title, run.date.UTC(), run.entry_number, run.run_date.UTC())
+	return err
+}
+
+func (s sql_storage) drafts() ([]draft, error) {
+	rows, err := s.query("select draft_number,auto_completion,date,entry_expair,adjusting_method,description,name,employee_name,entry_date,status,reviewer_name,review_date,comment,entry_number from drafts order by draft_number")
+	if err != nil {
+		return nil, err
+	}
+	var drafts []draft
+	index_of_draft := map[int]int{}
+	for rows.Next() {
+		var d draft
+		var entry_expair, review_date sql.NullTime
+		err = rows.Scan(&d.draft_number, &d.auto_completion, &d.date, &entry_expair, &d.adjusting_method, &d.description, &d.name, &d.employee_name, &d.entry_date,
+			&d.status, &d.reviewer_name, &review_date, &d.comment, &d.entry_number)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		d.entry_expair, d.review_date = entry_expair.Time, review_date.Time
+		index_of_draft[d.draft_number] = len(drafts)
+		drafts = append(drafts, d)
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	rows, err = s.query("select draft_number,account,value,quantity,barcode from draft_lines order by id")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var draft_number int
+		var line Account_value_quantity_barcode
+		err = rows.Scan(&draft_number, &line.Account, &line.value, &line.quantity, &line.barcode)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if index, ok := index_of_draft[draft_number]; ok {
+			drafts[index].lines = append(drafts[index].lines, line)
+		}
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	rows, err = s.query("select draft_number,day,start_hour,start_minute,end_hour,end_minute from draft_days order by id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var draft_number int
+		var day day_start_end
+		err = rows.Scan(&draft_number, &day.day, &day.start_hour, &day.start_minute, &day.end_hour, &day.end_minute)
+		if err != nil {
+			return nil, err
+		}
+		if index, ok := index_of_draft[draft_number]; ok {
+			drafts[index].array_day_start_end = append(drafts[index].array_day_start_end, day)
+		}
+	}
+	return drafts, rows.Err()
+}
+
+func (s sql_storage) insert_draft(d draft) error {
+	_, err := s.exec("insert into drafts(draft_number,auto_completion,date,entry_expair,adjusting_method,description,name,employee_name,entry_date,status,reviewer_name,review_date,comment,entry_number) values (?,?,?,?,?,?,?,?,?,?,?,?,?,?)",
+		d.draft_number, d.auto_completion, d.date.UTC(), null_time(d.entry_expair), d.adjusting_method, d.description, d.name, d.employee_name, d.entry_date.UTC(),
+		d.status, d.reviewer_name, null_time(d.review_date), d.comment, d.entry_number)
+	if err != nil {
+		return err
+	}
+	for _, line := range d.lines {
+		_, err = s.exec("insert into draft_lines(draft_number,account,value,quantity,barcode) values (?,?,?,?,?)", d.draft_number, line.Account, line.value, line.quantity, line.barcode)
+		if err != nil {
+			return err
+		}
+	}
+	for _, day := range d.array_day_start_end {
+		_, err = s.exec("insert into draft_days(draft_number,day,start_hour,start_minute,end_hour,end_minute) values (?,?,?,?,?,?)", d.draft_number, day.day, day.start_hour, day.start_minute, day.end_hour, day.end_minute)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s sql_storage) set_draft_review(draft_number int, status, reviewer_name string, review_date time.Time, comment string, entry_number int) error {
+	_, err := s.exec("update drafts set status=?,reviewer_name=?,review_date=?,comment=?,entry_number=? where draft_number=?", status, reviewer_name, review_date.UTC(), comment, entry_number, draft_number)
+	return err
+}
+
+func (s sql_storage) periods() ([]period, error) {
+	rows, err := s.query("select start_date,end_date,status from periods order by start_date")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var periods []period
+	for rows.Next() {
+		var p period
+		err = rows.Scan(&p.start_date, &p.end_date, &p.status)
+		if err != nil {
+			return nil, err
+		}
+		periods = append(periods, p)
+	}
+	return periods, rows.Err()
+}
+
+func (s sql_storage) insert_period(p period) error {
+	_, err := s.exec("insert into periods(start_date,end_date,status) values (?,?,?)", p.start_date.UTC(), p.end_date.UTC(), p.status)
+	return err
+}
+
+func (s sql_storage) delete_period(start_date, end_date time.Time) error {
+	_, err := s.exec("delete from periods where start_date=? and end_date=?", start_date.UTC(), end_date.UTC())
+	return err
+}
+
+func (s sql_storage) period_changes() ([]period_change, error) {
+	rows, err := s.query("select id,date,operation,start_date,end_date,reason,employee_name from period_changes order by id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var changes []period_change
+	for rows.Next() {
+		var change period_change
+		err = rows.Scan(&change.id, &change.date, &change.operation, &change.start_date, &change.end_date, &change.reason, &change.employee_name)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+func (s sql_storage) insert_period_change(change period_change) error {
+	_, err := s.exec("insert into period_changes(date,operation,start_date,end_date,reason,employee_name) values (?,?,?,?,?,?)",
+		change.date.UTC(), change.operation, change.start_date.UTC(), change.end_date.UTC(), change.reason, change.employee_name)
+	return err
+}
+
+func (s sql_storage) year_closings() ([]year_closing, error) {
+	rows, err := s.query("select id,end_date,date,employee_name,undone_by,undo_date from year_closings order by id")
+	if err != nil {
+		return nil, err
+	}
+	var closings []year_closing
+	index_of_id := map[int]int{}
+	for rows.Next() {
+		var closing year_closing
+		var undo_date sql.NullTime
+		err = rows.Scan(&closing.id, &closing.end_date, &closing.date, &closing.employee_name, &closing.undone_by, &undo_date)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		closing.undo_date = undo_date.Time
+		index_of_id[closing.id] = len(closings)
+		closings = append(closings, closing)
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	rows, err = s.query("select closing_id,entry_number from year_closing_entries order by id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, entry_number int
+		err = rows.Scan(&id, &entry_number)
+		if err != nil {
+			return nil, err
+		}
+		if index, ok := index_of_id[id]; ok {
+			closings[index].entry_numbers = append(closings[index].entry_numbers, entry_number)
+		}
+	}
+	return closings, rows.Err()
+}
+
+func (s sql_storage) insert_year_closing(closing year_closing) error {
+	_, err := s.exec("insert into year_closings(id,end_date,date,employee_name,undone_by,undo_date) values (?,?,?,?,?,?)",
+		closing.id, closing.end_date.UTC(), closing.date.UTC(), closing.employee_name, closing.undone_by, null_time(closing.undo_date))
+	if err != nil {
+		return err
+	}
+	for _, entry_number := range closing.entry_numbers {
+		_, err = s.exec("insert into year_closing_entries(closing_id,entry_number) values (?,?)", closing.id, entry_number)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s sql_storage) set_year_closing_undone(id int, employee_name string, date time.Time) error {
+	_, err := s.exec("update year_closings set undone_by=?,undo_date=? where id=?", employee_name, date.UTC(), id)
+	return err
+}
+
+// exchange_rates returns the rates of the currency by date
+func (s sql_storage) exchange_rates(currency string) ([]exchange_rate, error) {
+	rows, err := s.query("select currency,date,rate from exchange_rates where currency=? order by date", currency)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var rates []exchange_rate
+	for rows.Next() {
+		var rate exchange_rate
+		err = rows.Scan(&rate.currency, &rate.date, &rate.rate)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	return rates, rows.Err()
+}
+
+func (s sql_storage) insert_exchange_rate(rate exchange_rate) error {
+	_, err := s.exec("insert into exchange_rates(currency,date,rate) values (?,?,?)", rate.currency, rate.date.UTC(), rate.rate)
+	return err
+}
+
+func (s sql_storage) delete_exchange_rate(currency string, date time.Time) error {
+	_, err := s.exec("delete from exchange_rates where currency=? and date=?", currency, date.UTC())
+	return err
+}
+
+func (s sql_storage) inventory_accounts() ([]string, error) {
+	rows, err := s.query("select account from inventory")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var accounts []string
+	for rows.Next() {
+		var tag string
+		err = rows.Scan(&tag)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, tag)
+	}
+	return accounts, rows.Err()
+}
+
+// the empty layers are skipped here and not in the query because sqlite saves the quantities as text
+func (s sql_storage) inventory_layers(account, barcode, order_by_date_asc_or_desc string) ([]journal_tag, error) {
+	rows, err := s.query("select id,price,quantity from inventory where account=? and barcode=? order by date "+order_by_date_asc_or_desc+",id "+order_by_date_asc_or_desc, account, barcode)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var inventory []journal_tag
+	for rows.Next() {
+		var tag journal_tag
+		err = rows.Scan(&tag.id, &tag.price, &tag.quantity)
+		if err != nil {
+			return nil, err
+		}
+		if tag.quantity.IsPositive() {
+			inventory = append(inventory, tag)
+		}
+	}
+	return inventory, rows.Err()
+}
+
+// inventory_layer_of_line returns the last layer that the line can have made, the layers don't keep the entry numbers
+func (s sql_storage) inventory_layer_of_line(tag journal_tag) (journal_tag, error) {
+	layer := journal_tag{account: tag.account, barcode: tag.barcode, date: tag.date}
+	err := s.query_row("select id,price,quantity from inventory where account=? and barcode=? and date=? order by id desc limit 1",
+		tag.account, tag.barcode, tag.date.UTC()).Scan(&layer.id, &layer.price, &layer.quantity)
+	return layer, err
+}
+
+func (s sql_storage) insert_inventory(tag journal_tag) error {
+	_, err := s.exec("insert into inventory(date,account,price,quantity,barcode,entry_expair,name,employee_name,entry_date)values (?,?,?,?,?,?,?,?,?)",
+		tag.date.UTC(), tag.account, tag.price, tag.quantity, tag.barcode, null_time(tag.entry_expair), tag.name, tag.employee_name, tag.entry_date.UTC())
+	return err
+}
+
+// zero is saved as 0 by every database, on sqlite too because decimal writes it without places
+func (s sql_storage) delete_empty_inventory() error {
+	_, err := s.exec("delete from inventory where quantity=0")
+	return err
+}
+
+func (s sql_storage) weighted_average(account string) error {
+	value, err := sum_column(s.query("select value from journal where account=?", account))
+	if err != nil {
+		return err
+	}
+	quantity, err := sum_column(s.query("select quantity from journal where account=?", account))
+	if err != nil || quantity.IsZero() {
+		return err
+	}
+	_, err = s.exec("update inventory set price=? where account=?", value.Div(quantity), account)
+	return err
+}
+
+func (s sql_storage) set_inventory_layer_quantity(id int, quantity decimal.Decimal) error {
+	_, err := s.exec("update inventory set quantity=? where id=?", quantity, id)
+	return err
+}
+
+func (s sql_storage) delete_inventory_layer(id int) error {
+	_, err := s.exec("delete from inventory where id=?", id)
+	return err
+}
+
+func (s sql_storage) snapshot_dates() ([]time.Time, error) {
+	rows, err := s.query("select date from snapshot")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var dates []time.Time
+	for rows.Next() {
+		var date time.Time
+		err = rows.Scan(&date)
+		if err != nil {
+			return nil, err
+		}
+		dates = append(dates, date.UTC())
+	}
+	return dates, rows.Err()
+}
+
+func (s sql_storage) snapshot(date time.Time) ([]balance_snapshot, error) {
+	rows, err := s.query("select account_flow,account,name,value,quantity from snapshot_balance where date=? order by id", date.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var snapshot []balance_snapshot
+	for rows.Next() {
+		row := balance_snapshot{date: date}
+		err = rows.Scan(&row.account_flow, &row.account, &row.name, &row.value, &row.quantity)
+		if err != nil {
+			return nil, err
+		}
+		snapshot = append(snapshot, row)
+	}
+	return snapshot, rows.Err()
+}
+
+func (s sql_storage) insert_snapshot(date time.Time, rows []balance_snapshot) error {
+	_, err := s.exec("insert into snapshot(date) values (?)", date.UTC())
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		_, err = s.exec("insert into snapshot_balance(date,account_flow,account,name,value,quantity) values (?,?,?,?,?,?)",
+			date.UTC(), row.account_flow, row.account, row.name, row.value, row.quantity)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the new values are added in go because sqlite would add them as float
+func (s sql_storage) add_to_snapshots(after time.Time, rows []balance_snapshot) error {
+	dates, err := s.snapshot_dates()
+	if err != nil {
+		return err
+	}
+	for _, date := range dates {
+		if !date.After(after) {
+			continue
+		}
+		for _, row := range rows {
+			var id int
+			var value, quantity decimal.Decimal
+			err = s.query_row("select id,value,quantity from snapshot_balance where date=? and account_flow=? and account=? and name=?",
+				date, row.account_flow, row.account, row.name).Scan(&id, &value, &quantity)
+			switch err {
+			case sql.ErrNoRows:
+				_, err = s.exec("insert into snapshot_balance(date,account_flow,account,name,value,quantity) values (?,?,?,?,?,?)",
+					date, row.account_flow, row.account, row.name, row.value, row.quantity)
+			case nil:
+				_, err = s.exec("update snapshot_balance set value=?,quantity=? where id=?", value.Add(row.value), quantity.Add(row.quantity), id)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s sql_storage) delete_snapshots(after time.Time) error {
+	_, err := s.exec("delete from snapshot_balance where date>?", after.UTC())
+	if err != nil {
+		return err
+	}
+	_, err = s.exec("delete from snapshot where date>?", after.UTC())
+	return err
+}
+
+func scan_journal(rows *sql.Rows, err error) ([]journal_tag, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var journal []journal_tag
+	for rows.Next() {
+		var tag journal_tag
+		var entry_expair sql.NullTime
+		err = rows.Scan(&tag.id, &tag.date, &tag.entry_number, &tag.account, &tag.value, &tag.price, &tag.quantity, &tag.barcode, &entry_expair, &tag.description, &tag.name, &tag.employee_name, &tag.entry_date, &tag.reverse, &tag.currency, &tag.foreign_value, &tag.rate)
+		if err != nil {
+			return nil, err
+		}
+		tag.entry_expair = entry_expair.Time
+		journal = append(journal, tag)
+	}
+	return journal, rows.Err()
+}
+
+func sum_column(rows *sql.Rows, err error) (decimal.Decimal, error) {
+	if err != nil {
+		return decimal.Zero, err
+	}
+	defer rows.Close()
+	var sum decimal.Decimal
+	for rows.Next() {
+		var number decimal.Decimal
+		err = rows.Scan(&number)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		sum = sum.Add(number)
+	}
+	return sum, rows.Err()
+}
+
+// the zero time is saved as null because it is out of the range of the datetime columns
+func null_time(date time.Time) sql.NullTime {
+	return sql.NullTime{Time: date.UTC(), Valid: !date.IsZero()}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// the dates are saved in UTC so the ranges that are given in another zone find the same lines
+func TestSqliteDatesInOtherZones(t *testing.T) {
+	s := test_company(t, "sqlite3", "")
+	s.initialize()
+	zone := time.FixedZone("+03", 3*60*60)
+	entry := post(t, s, test_day.In(zone), line("cash", 1000, 1000), line("capital", 1000, 1000))
+	start, end := test_day.Add(-time.Hour).In(zone), test_day.Add(time.Hour).In(zone)
+	lines, err := s.db.journal_of_account_between("cash", start, end)
+	check(t, err)
+	if len(lines) != 1 {
+		t.Errorf("journal_of_account_between found %d lines not 1", len(lines))
+	}
+	lines, err = s.db.journal_of_entry_number_between(uint(entry[0].entry_number), start, end)
+	check(t, err)
+	if len(lines) != 2 {
+		t.Errorf("journal_of_entry_number_between found %d lines not 2", len(lines))
+	}
+}
+
+// the real columns of the sqlite databases before the decimals are copied to text columns and keep their values,
+// the values that are posted after that are saved with all their places
+func TestSqliteDecimalMigration(t *testing.T) {
+	data_source_name := t.TempDir() + "/acc.sqlite"
+	db, err := open_storage("sqlite3", data_source_name, "acc")
+	check(t, err)
+	old := db.(sql_storage)
+	released := migrations
+	migrations = migrations[:2]
+	check(t, old.migrate(nil))
+	migrations = released
+	for _, row := range []struct {
+		account         string
+		value, quantity float64
+	}{{"cash", 1000.1, 1000.1}, {"capital", 1000.1, 1000.1}} {
+		_, err = old.exec("insert into journal(date,entry_number,account,value,price,quantity,barcode,description,name,employee_name,entry_date,reverse) values (?,?,?,?,?,?,?,?,?,?,?,?)",
+			test_day, 1, row.account, row.value, 1, row.quantity, "", "", "", "", test_day, false)
+		check(t, err)
+	}
+	check(t, old.pool.Close())
+
+	s := test_company(t, "sqlite3", data_source_name)
+	s.initialize()
+	expect_balance(t, s, "cash", 1000.1)
+	big := decimal.RequireFromString("9999999999999999.99")
+	post(t, s, test_day, Account_value_quantity_barcode{"cash", big, number(1), ""}, Account_value_quantity_barcode{"capital", big, number(1), ""})
+	if balance, expected := balance_of(t, s, "cash"), decimal.RequireFromString("10000000000001000.09"); !balance.Equal(expected) {
+		t.Errorf("the balance of cash is %s not %s", balance, expected)
+	}
+	var types string
+	check(t, s.db.(sql_storage).query_row("select group_concat(distinct typeof(value)||typeof(price)||typeof(quantity)) from journal").Scan(&types))
+	if types != "texttexttext" {
+		t.Errorf("the decimals of the journal are saved as %s not text", types)
+	}
+}
+
+// the sqlite file keeps the journal and the inventory so the company that opens it again goes on from them
+func TestSqliteReopen(t *testing.T) {
+	s := test_company(t, "sqlite3", "")
+	s.initialize()
+	post(t, s, test_day, line("cash", 1000, 1000), line("capital", 1000, 1000))
+	post(t, s, test_day, line("panadol", 100, 10), line("cash", -100, -100))
+	post(t, s, test_day.AddDate(0, 0, 1), line("panadol", 120, 10), line("cash", -120, -120))
+
+	reopened := test_company(t, "sqlite3", s.DataSourceName)
+	reopened.initialize()
+	expect_balance(t, reopened, "cash", 780)
+	// fifo takes the first layer of 10 for 100 and 5 of the second layer for 60
+	post(t, reopened, test_day.AddDate(0, 0, 2), line("panadol", -1, -15), line("cost of book", 160, 160))
+	expect_balance(t, reopened, "panadol", 60)
+	layers, err := reopened.db.inventory_layers("panadol", "", "asc")
+	check(t, err)
+	if len(layers) != 1 || !layers[0].quantity.Equal(number(5)) || !layers[0].price.Equal(number(12)) {
+		t.Errorf("the inventory of panadol is %v not 5 for 12", layers)
+	}
+}
+
+// the entry that fails after some of its lines took from the inventory leaves nothing behind
+func TestSqliteRollback(t *testing.T) {
+	s := test_company(t, "sqlite3", "")
+	s.initialize()
+	post(t, s, test_day, line("cash", 1000, 1000), line("capital", 1000, 1000))
+	post(t, s, test_day, line("panadol", 100, 10), line("cash", -100, -100))
+	post(t, s, test_day, line("aspirin", 100, 10), line("cash", -100, -100))
+	_, err := s.journal_entry([]Account_value_quantity_barcode{line("panadol", -1, -5), line("aspirin", -1, -50), line("cost of book", 1, 1)}, true, false, test_day.AddDate(0, 0, 1), time.Time{}, "", "", "", "clerk", nil)
+	if _, ok := err.(ErrInsufficientInventory); !ok {
+		t.Fatalf("the entry should be ErrInsufficientInventory not %v", err)
+	}
+	journal, err := s.db.journal()
+	check(t, err)
+	if len(journal) != 6 {
+		t.Errorf("the journal has %d lines not 6", len(journal))
+	}
+	layers, err := s.db.inventory_layers("panadol", "", "asc")
+	check(t, err)
+	if len(layers) != 1 || !layers[0].quantity.Equal(number(10)) {
+		t.Errorf("the inventory of panadol is %v not 10", layers)
+	}
+	// the entry number of the failed entry is not taken
+	entry := post(t, s, test_day.AddDate(0, 0, 1), line("panadol", -1, -5), line("cost of book", 50, 50))
+	if entry[0].entry_number != 4 {
+		t.Errorf("the entry after the failed entry has the number %d not 4", entry[0].entry_number)
+	}
+	check(t, s.reverse_entry(uint(entry[0].entry_number), "boss"))
+	layers, err = s.db.inventory_layers("panadol", "", "asc")
+	check(t, err)
+	var quantity decimal.Decimal
+	for _, layer := range layers {
+		quantity = quantity.Add(layer.quantity)
+	}
+	if !quantity.Equal(number(10)) {
+		t.Errorf("the inventory of panadol is %s not 10 after the reverse", quantity)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// the templates are charts of accounts that pass the checks of initialize with all the special accounts filled,
+// the caller sets DriverName, DataSourceName and Database_name then changes the chart as it needs
+var chart_templates = map[string]func() (map[string]string, []account){
+	"trading":  trading_template,
+	"service":  service_template,
+	"ifrs_sme": ifrs_sme_template,
+}
+
+func chart_template_names() []string {
+	var names []string
+	for name := range chart_templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func chart_template(name string) (Financial_accounting, error) {
+	template, ok := chart_templates[name]
+	if !ok {
+		return Financial_accounting{}, ErrInvalidConfig{fmt.Sprint("the template ", name, " is not in ", chart_template_names())}
+	}
+	special_accounts, accounts := template()
+	var c config_file
+	for _, field := range c.special_accounts() {
+		*field.value = special_accounts[field.key]
+	}
+	s := c.financial_accounting()
+	s.date_layout = []string{"2006-01-02 15:04:05.999999999 -0700 MST"}
+	s.value_places, s.quantity_places = default_value_places, default_quantity_places
+	return s, s.use_accounts(accounts)
+}
+
+func default_special_accounts() map[string]string {
+	return map[string]string{
+		"assets":                    "assets",
+		"current_assets":            "current_assets",
+		"cash_and_cash_equivalents": "cash_and_cash_equivalents",
+		"short_term_investments":    "short_term_investments",
+		"receivables":               "receivables",
+		"inventory":                 "inventory",
+		"liabilities":               "liabilities",
+		"current_liabilities":       "current_liabilities",
+		"equity":                    "equity",
+		"retained_earnings":         "retained_earnings",
+		"dividends":                 "dividends",
+		"income_statement":          "income_statement",
+		"ebitda":                    "ebitda",
+		"sales":                     "sales",
+		"cost_of_goods_sold":        "cost_of_goods_sold",
+		"discounts":                 "discounts",
+		"invoice_discount":          "invoice_discount",
+		"interest_expense":          "interest_expense",
+	}
+}
+
+// trading_template is for a small business that buys and sells goods, the goods are fifo
+func trading_template() (map[string]string, []account) {
+	return default_special_accounts(), []account{
+		{false, "", "", "assets", false, "1000", account_metadata{}},
+		{false, "", "assets", "current_assets", false, "1100", account_metadata{}},
+		{false, "", "current_assets", "cash_and_cash_equivalents", false, "1110", account_metadata{}},
+		{false, "", "cash_and_cash_equivalents", "cash on hand", false, "1111", account_metadata{}},
+		{false, "", "cash_and_cash_equivalents", "bank", false, "1112", account_metadata{}},
+		{false, "", "current_assets", "short_term_investments", false, "1120", account_metadata{cash_flow_category: "investing"}},
+		{false, "", "current_assets", "receivables", false, "1130", account_metadata{cash_flow_category: "operating"}},
+		{false, "", "receivables", "trade receivables", false, "1131", account_metadata{}},
+		{true, "", "receivables", "allowance for doubtful accounts", false, "1139", account_metadata{contra: true}},
+		{false, "", "current_assets", "inventory", false, "1140", account_metadata{cash_flow_category: "operating"}},
+		{false, "fifo", "inventory", "merchandise", false, "1141", account_metadata{}},
+		{false, "", "current_assets", "prepaid expenses", false, "1150", account_metadata{cash_flow_category: "operating"}},
+		{false, "", "assets", "non_current_assets", false, "1200", account_metadata{cash_flow_category: "investing"}},
+		{false, "", "non_current_assets", "equipment", false, "1210", account_metadata{}},
+		{true, "", "equipment", "accumulated depreciation", false, "1219", account_metadata{contra: true}},
+		{true, "", "", "liabilities", false, "2000", account_metadata{}},
+		{true, "", "liabilities", "current_liabilities", false, "2100", account_metadata{cash_flow_category: "operating"}},
+		{true, "", "current_liabilities", "accounts payable", false, "2110", account_metadata{}},
+		{true, "", "current_liabilities", "tax payable", false, "2120", account_metadata{}},
+		{true, "", "current_liabilities", "accrued expenses", false, "2130", account_metadata{}},
+		{true, "", "liabilities", "non_current_liabilities", false, "2200", account_metadata{cash_flow_category: "financing"}},
+		{true, "", "non_current_liabilities", "long term loans", false, "2210", account_metadata{}},
+		{true, "", "", "equity", false, "3000", account_metadata{}},
+		{true, "", "equity", "capital", false, "3100", account_metadata{cash_flow_category: "financing"}},
+		{true, "", "equity", "retained_earnings", false, "3200", account_metadata{}},
+		{false, "", "retained_earnings", "dividends", false, "3300", account_metadata{contra: true, cash_flow_category: "financing"}},
+		{true, "", "retained_earnings", "income_statement", false, "3900", account_metadata{cash_flow_category: "operating"}},
+		{true, "", "income_statement", "ebitda", false, "3910", account_metadata{}},
+		{true, "", "ebitda", "sales", false, "4000", account_metadata{}},
+		{true, "", "sales", "sales of goods", false, "4100", account_metadata{}},
+		{false, "", "sales", "sales returns", false, "4900", account_metadata{contra: true}},
+		{false, "", "ebitda", "cost_of_goods_sold", false, "5000", account_metadata{}},
+		{false, "", "cost_of_goods_sold", "cost of merchandise", false, "5100", account_metadata{}},
+		{false, "", "ebitda", "discounts", false, "5500", account_metadata{}},
+		{false, "", "discounts", "invoice_discount", false, "5510", account_metadata{}},
+		{false, "", "discounts", "sales discounts", false, "5520", account_metadata{}},
+		{false, "", "ebitda", "operating expenses", false, "6000", account_metadata{}},
+		{false, "", "operating expenses", "salaries", false, "6100", account_metadata{}},
+		{false, "", "operating expenses", "rent", false, "6200", account_metadata{}},
+		{false, "", "operating expenses", "utilities", false, "6300", account_metadata{}},
+		{false, "", "income_statement", "depreciation expense", false, "6900", account_metadata{}},
+		{false, "", "income_statement", "interest_expense", false, "7000", account_metadata{}},
+		{false, "", "income_statement", "income tax expense", false, "8000", account_metadata{}},
+	}
+}
+
+// service_template is for a business that sells services, inventory is kept for the supplies only
+func service_template() (map[string]string, []account) {
+	special_accounts := default_special_accounts()
+	special_accounts["sales"] = "service revenue"
+	special_accounts["cost_of_goods_sold"] = "cost of services"
+	return special_accounts, []account{
+		{false, "", "", "assets", false, "1000", account_metadata{}},
+		{false, "", "assets", "current_assets", false, "1100", account_metadata{}},
+		{false, "", "current_assets", "cash_and_cash_equivalents", false, "1110", account_metadata{}},
+		{false, "", "cash_and_cash_equivalents", "cash on hand", false, "1111", account_metadata{}},
+		{false, "", "cash_and_cash_equivalents", "bank", false, "1112", account_metadata{}},
+		{false, "", "current_assets", "short_term_investments", false, "1120", account_metadata{cash_flow_category: "investing"}},
+		{false, "", "current_assets", "receivables", false, "1130", account_metadata{cash_flow_category: "operating"}},
+		{false, "", "receivables", "customer receivables", false, "1131", account_metadata{}},
+		{false, "", "receivables", "unbilled revenue", false, "1132", account_metadata{}},
+		{true, "", "receivables", "allowance for doubtful accounts", false, "1139", account_metadata{contra: true}},
+		{false, "", "current_assets", "inventory", false, "1140", account_metadata{cash_flow_category: "operating"}},
+		{false, "wma", "inventory", "supplies", false, "1141", account_metadata{}},
+		{false, "", "current_assets", "prepaid expenses", false, "1150", account_metadata{cash_flow_category: "operating"}},
+		{false, "", "assets", "non_current_assets", false, "1200", account_metadata{cash_flow_category: "investing"}},
+		{false, "", "non_current_assets", "office equipment", false, "1210", account_metadata{}},
+		{true, "", "office equipment", "accumulated depreciation", false, "1219", account_metadata{contra: true}},
+		{true, "", "", "liabilities", false, "2000", account_metadata{}},
+		{true, "", "liabilities", "current_liabilities", false, "2100", account_metadata{cash_flow_category: "operating"}},
+		{true, "", "current_liabilities", "accounts payable", false, "2110", account_metadata{}},
+		{true, "", "current_liabilities", "unearned revenue", false, "2120", account_metadata{}},
+		{true, "", "current_liabilities", "tax payable", false, "2130", account_metadata{}},
+		{true, "", "current_liabilities", "salaries payable", false, "2140", account_metadata{}},
+		{true, "", "liabilities", "non_current_liabilities", false, "2200", account_metadata{cash_flow_category: "financing"}},
+		{true, "", "non_current_liabilities", "long term loans", false, "2210", account_metadata{}},
+		{true, "", "", "equity", false, "3000", account_metadata{}},
+		{true, "", "equity", "capital", false, "3100", account_metadata{cash_flow_category: "financing"}},
+		{true, "", "equity", "retained_earnings", false, "3200", account_metadata{}},
+		{false, "", "retained_earnings", "dividends", false, "3300", account_metadata{contra: true, cash_flow_category: "financing"}},
+		{true, "", "retained_earnings", "income_statement", false, "3900", account_metadata{cash_flow_category: "operating"}},
+		{true, "", "income_statement", "ebitda", false, "3910", account_metadata{}},
+		{true, "", "ebitda", "service revenue", false, "4000", account_metadata{}},
+		{true, "", "service revenue", "consulting revenue", false, "4100", account_metadata{}},
+		{true, "", "service revenue", "subscription revenue", false, "4200", account_metadata{}},
+		{false, "", "ebitda", "cost of services", false, "5000", account_metadata{}},
+		{false, "", "cost of services", "subcontractors", false, "5100", account_metadata{}},
+		{false, "", "cost of services", "supplies used", false, "5200", account_metadata{}},
+		{false, "", "ebitda", "discounts", false, "5500", account_metadata{}},
+		{false, "", "discounts", "invoice_discount", false, "5510", account_metadata{}},
+		{false, "", "ebitda", "operating expenses", false, "6000", account_metadata{}},
+		{false, "", "operating expenses", "salaries", false, "6100", account_metadata{}},
+		{false, "", "operating expenses", "rent", false, "6200", account_metadata{}},
+		{false, "", "operating expenses", "software", false, "6300", account_metadata{}},
+		{false, "", "operating expenses", "marketing", false, "6400", account_metadata{}},
+		{false, "", "income_statement", "depreciation expense", false, "6900", account_metadata{}},
+		{false, "", "income_statement", "interest_expense", false, "7000", account_metadata{}},
+		{false, "", "income_statement", "income tax expense", false, "8000", account_metadata{}},
+	}
+}
+
+// ifrs_sme_template follows the line items of the statements in IFRS for SMEs
+func ifrs_sme_template() (map[string]string, []account) {
+	special_accounts := default_special_accounts()
+	special_accounts["receivables"] = "trade and other receivables"
+	special_accounts["inventory"] = "inventories"
+	special_accounts["current_liabilities"] = "current liabilities"
+	special_accounts["sales"] = "revenue"
+	special_accounts["cost_of_goods_sold"] = "cost of sales"
+	special_accounts["discounts"] = "sales discounts and rebates"
+	special_accounts["interest_expense"] = "finance costs"
+	return special_accounts, []account{
+		{false, "", "", "assets", false, "1000", account_metadata{}},
+		{false, "", "assets", "current_assets", false, "1100", account_metadata{}},
+		{false, "", "current_assets", "cash_and_cash_equivalents", false, "1110", account_metadata{}},
+		{false, "", "cash_and_cash_equivalents", "cash at bank and in hand", false, "1111", account_metadata{}},
+		{false, "", "cash_and_cash_equivalents", "short term deposits", false, "1112", account_metadata{}},
+		{false, "", "current_assets", "short_term_investments", false, "1120", account_metadata{cash_flow_category: "investing"}},
+		{false, "", "current_assets", "trade and other receivables", false, "1130", account_metadata{cash_flow_category: "operating"}},
+		{false, "", "trade and other receivables", "trade receivables", false, "1131", account_metadata{}},
+		{false, "", "trade and other receivables", "other receivables", false, "1132", account_metadata{}},
+		{true, "", "trade and other receivables", "impairment of trade receivables", false, "1139", account_metadata{contra: true}},
+		{false, "", "current_assets", "inventories", false, "1140", account_metadata{cash_flow_category: "operating"}},
+		{false, "wma", "inventories", "raw materials", false, "1141", account_metadata{}},
+		{false, "wma", "inventories", "finished goods", false, "1142", account_metadata{}},
+		{false, "", "current_assets", "current tax assets", false, "1150", account_metadata{cash_flow_category: "operating"}},
+		{false, "", "assets", "non current assets", false, "1200", account_metadata{cash_flow_category: "investing"}},
+		{false, "", "non current assets", "property, plant and equipment", false, "1210", account_metadata{}},
+		{true, "", "property, plant and equipment", "accumulated depreciation", false, "1219", account_metadata{contra: true}},
+		{false, "", "non current assets", "intangible assets", false, "1220", account_metadata{}},
+		{true, "", "intangible assets", "accumulated amortisation", false, "1229", account_metadata{contra: true}},
+		{false, "", "non current assets", "investment property", false, "1230", account_metadata{}},
+		{true, "", "", "liabilities", false, "2000", account_metadata{}},
+		{true, "", "liabilities", "current liabilities", false, "2100", account_metadata{}},
+		{true, "", "current liabilities", "trade and other payables", false, "2110", account_metadata{cash_flow_category: "operating"}},
+		{true, "", "current liabilities", "current tax liabilities", false, "2120", account_metadata{cash_flow_category: "operating"}},
+		{true, "", "current liabilities", "short term provisions", false, "2130", account_metadata{cash_flow_category: "operating"}},
+		{true, "", "current liabilities", "short term borrowings", false, "2140", account_metadata{cash_flow_category: "financing"}},
+		{true, "", "liabilities", "non current liabilities", false, "2200", account_metadata{}},
+		{true, "", "non current liabilities", "long term borrowings", false, "2210", account_metadata{cash_flow_category: "financing"}},
+		{true, "", "non current liabilities", "deferred tax liabilities", false, "2220", account_metadata{cash_flow_category: "operating"}},
+		{true, "", "", "equity", false, "3000", account_metadata{}},
+		{true, "", "equity", "share capital", false, "3100", account_metadata{cash_flow_category: "financing"}},
+		{true, "", "equity", "share premium", false, "3110", account_metadata{cash_flow_category: "financing"}},
+		{true, "", "equity", "other reserves", false, "3120", account_metadata{}},
+		{true, "", "equity", "retained_earnings", false, "3200", account_metadata{}},
+		{false, "", "retained_earnings", "dividends", false, "3300", account_metadata{contra: true, cash_flow_category: "financing"}},
+		{true, "", "retained_earnings", "income_statement", false, "3900", account_metadata{cash_flow_category: "operating"}},
+		{true, "", "income_statement", "ebitda", false, "3910", account_metadata{}},
+		{true, "", "ebitda", "revenue", false, "4000", account_metadata{}},
+		{true, "", "revenue", "revenue from sale of goods", false, "4100", account_metadata{}},
+		{true, "", "revenue", "revenue from services", false, "4200", account_metadata{}},
+		{true, "", "ebitda", "other income", false, "4500", account_metadata{}},
+		{false, "", "ebitda", "cost of sales", false, "5000", account_metadata{}},
+		{false, "", "cost of sales", "cost of goods sold", false, "5100", account_metadata{}},
+		{false, "", "ebitda", "sales discounts and rebates", false, "5500", account_metadata{}},
+		{false, "", "sales discounts and rebates", "invoice_discount", false, "5510", account_metadata{}},
+		{false, "", "ebitda", "distribution costs", false, "6000", account_metadata{}},
+		{false, "", "ebitda", "administrative expenses", false, "6100", account_metadata{}},
+		{false, "", "ebitda", "other expenses", false, "6200", account_metadata{}},
+		{false, "", "income_statement", "depreciation and amortisation", false, "6900", account_metadata{}},
+		{false, "", "income_statement", "finance costs", false, "7000", account_metadata{}},
+		{false, "", "income_statement", "income tax expense", false, "8000", account_metadata{}},
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// every chart template passes the checks of initialize and can post and make the statements
+func TestChartTemplates(t *testing.T) {
+	for _, name := range chart_template_names() {
+		s, err := chart_template(name)
+		check(t, err)
+		s.DriverName = "memory"
+		check(t, s.check_config())
+		s.initialize()
+		leaf := func(father string, not_under string) string {
+			t.Helper()
+			leaves, err := s.account_leaves(father)
+			check(t, err)
+			for _, a := range leaves {
+				if (not_under == "" || !s.is_father(not_under, a)) && !IS_IN(a, s.inventory_accounts) {
+					return a
+				}
+			}
+			t.Fatalf("%s: %s has no leaf", name, father)
+			return ""
+		}
+		cash, capital, interest := leaf(s.cash_and_cash_equivalents, ""), leaf(s.equity, s.retained_earnings), leaf(s.interest_expense, "")
+		post(t, s, test_day, line(cash, 1000, 1000), line(capital, 1000, 1000))
+		post(t, s, test_day, line(interest, 40, 40), line(cash, -40, -40))
+		statements, _, _, err := s.financial_statements(test_day.AddDate(0, 0, -1), test_day.AddDate(0, 1, 0), 1, nil, false)
+		check(t, err)
+		if balance := statements[0][s.cash_and_cash_equivalents][cash]["all"]["value"]["ending_balance"]; !balance.Equal(number(960)) {
+			t.Errorf("%s: the ending balance of %s is %s not 960", name, cash, balance)
+		}
+		if len(s.inventory_accounts) != 0 {
+			_, err = s.journal_entry([]Account_value_quantity_barcode{line(s.inventory_accounts[0], 100, 10), line(cash, -100, -100)}, true, false, test_day, time.Time{}, "", "", "", "clerk", nil)
+			check(t, err)
+		}
+	}
+	if _, err := chart_template("nothing"); err == nil {
+		t.Error("the unknown template should be an error")
+	}
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// a transaction template is a named entry like sell book or render service, the clerk posts it by template_entry
+// with the inputs of its parameters and the lines are made in order then posted by post_entry.
+// the amount and the quantity of the lines are formulas of the parameters and the lines before them like
+//
+//	quantity*price-line2
+//
+// where line2 is the value of the second line of the template. the templates are in the config like auto_complete_entries
+// so rename and merge change them and save them to the file of the config
+
+type transaction_template struct {
+	name       string
+	parameters []template_parameter
+	lines      []template_line
+}
+
+type template_parameter struct {
+	name string // a letter or _ then letters, digits or _ so it can be used in the formulas
+	kind string // value, quantity, percent, account or barcode
+}
+
+type template_line struct {
+	account  string // the account, its code or the name of an account parameter
+	barcode  string // empty, the barcode or the name of a barcode parameter
+	method   string // fixed, percent_of_value, percent_of_line or formula
+	amount   string // the number for fixed, the percent for percent_of_value and percent_of_line, the value for formula
+	of       string // the value parameter for percent_of_value and the line like line1 for percent_of_line
+	quantity string // the formula of the quantity, empty means the quantity is the value
+}
+
+// the values of the lines with the inventory accounts and negative quantities are the cost of the quantity,
+// so the line that takes the cost out of the inventory can be -line1 whatever the amount of line1 is.
+// the entries that match an approval rule are refused like journal_entry refuses them, their lines are returned when
+// insert is false so they can be saved by save_draft
+func (s Financial_accounting) template_entry(template_name string, inputs map[string]string, insert bool, date time.Time,
+	description, name, employee_name string) ([]journal_tag, error) {
+	template, ok := s.transaction_template(template_name)
+	if !ok {
+		return nil, ErrUnknownTemplate{template_name}
+	}
+	var all_array_to_insert []journal_tag
+	err := s.db.transaction(func(tx storage) error {
+		err := tx.lock_ledger()
+		if err != nil {
+			return err
+		}
+		array_of_entry, err := s.template_lines(tx, template, inputs)
+		if err != nil {
+			return err
+		}
+		poster := s
+		poster.db = tx
+		all_array_to_insert, err = poster.post_entry(array_of_entry, insert, false, date, time.Time{}, "", description, name, employee_name, nil, true)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return all_array_to_insert, nil
+}
+
+func (s Financial_accounting) transaction_template(name string) (transaction_template, bool) {
+	for _, template := range s.transaction_templates {
+		if template.name == name {
+			return template, true
+		}
+	}
+	return transaction_template{}, false
+}
+
+// template_lines makes the lines of the entry from the inputs, every parameter should have an input and nothing else
+func (s Financial_accounting) template_lines(tx storage, template transaction_template, inputs map[string]string) ([]Account_value_quantity_barcode, error) {
+	numbers := map[string]decimal.Decimal{}
+	for _, parameter := range template.parameters {
+		input, ok := inputs[parameter.name]
+		if !ok {
+			return nil, ErrInvalidEntry{nil, fmt.Sprint("the input of ", parameter.name, " in ", template.name, " is missing")}
+		}
+		if IS_IN(parameter.kind, []string{"value", "quantity", "percent"}) {
+			number, err := decimal.NewFromString(strings.TrimSpace(input))
+			if err != nil {
+				return nil, ErrInvalidEntry{nil, fmt.Sprint("the input of ", parameter.name, " in ", template.name, " should be a number not ", input)}
+			}
+			numbers[parameter.name] = number
+		}
+	}
+	for key := range inputs {
+		if template.parameter(key) == nil {
+			return nil, ErrInvalidEntry{nil, fmt.Sprint(key, " is not a parameter of ", template.name)}
+		}
+	}
+	tree := s.chart_tree()
+	var array_of_entry []Account_value_quantity_barcode
+	for index, line := range template.lines {
+		lookup := func(name string) (decimal.Decimal, error) {
+			if number, ok := numbers[name]; ok {
+				return number, nil
+			}
+			if line_index, ok := line_reference(name); ok && line_index < index {
+				return array_of_entry[line_index].value, nil
+			}
+			return decimal.Zero, ErrInvalidConfig{fmt.Sprint(name, " in line", index+1, " of ", template.name, " is not a number parameter or a line before it")}
+		}
+		entry := Account_value_quantity_barcode{Account: line.account, barcode: line.barcode}
+		if parameter := template.parameter(line.account); parameter != nil && parameter.kind == "account" {
+			entry.Account = inputs[line.account]
+		}
+		if name, ok := tree.codes[entry.Account]; ok {
+			entry.Account = name
+		}
+		if parameter := template.parameter(line.barcode); parameter != nil && parameter.kind == "barcode" {
+			entry.barcode = inputs[line.barcode]
+		}
+		var err error
+		entry.value, err = line_value(line, lookup)
+		if err != nil {
+			return nil, err
+		}
+		entry.quantity = entry.value
+		if line.quantity != "" {
+			entry.quantity, err = evaluate_expression(line.quantity, lookup)
+			if err != nil {
+				return nil, err
+			}
+		}
+		entry.value = s.round_value(entry.value)
+		entry.quantity = s.round_quantity(entry.quantity)
+		if !s.is_account(entry.Account) {
+			return nil, ErrUnknownAccount{entry.Account}
+		}
+		costs, err := s.cost_flow(tx, entry.Account, entry.quantity, entry.barcode, false)
+		if err != nil {
+			return nil, err
+		}
+		if !costs.IsZero() {
+			entry.value = costs.Neg()
+		}
+		array_of_entry = append(array_of_entry, entry)
+	}
+	return array_of_entry, nil
+}
+
+func line_value(line template_line, lookup func(string) (decimal.Decimal, error)) (decimal.Decimal, error) {
+	amount, err := evaluate_expression(line.amount, lookup)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	switch line.method {
+	case "fixed", "formula":
+		return amount, nil
+	case "percent_of_value", "percent_of_line":
+		of, err := lookup(line.of)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		return amount.Mul(of).Div(decimal.NewFromInt(100)), nil
+	}
+	return decimal.Zero, ErrInvalidConfig{fmt.Sprint(line.method, " is not in ", template_line_methods)}
+}
+
+func (t transaction_template) parameter(name string) *template_parameter {
+	for index := range t.parameters {
+		if t.parameters[index].name == name {
+			return &t.parameters[index]
+		}
+	}
+	return nil
+}
+
+// line_reference returns the index of line1, line2... in the lines of the template
+func line_reference(name string) (int, bool) {
+	if !strings.HasPrefix(name, "line") {
+		return 0, false
+	}
+	number, err := strconv.Atoi(strings.TrimPrefix(name, "line"))
+	if err != nil || number < 1 {
+		return 0, false
+	}
+	return number - 1, true
+}
+
+// template_violations checks the templates like template_entry would, the formulas are checked with every
+// number parameter and every line before the line
+func (s Financial_accounting) template_violations() []violation {
+	var violations []violation
+	add := func(severity, account string, a ...interface{}) {
+		violations = append(violations, violation{severity, account, 0, fmt.Sprint(a...)})
+	}
+	tree := s.chart_tree()
+	templates := map[string]bool{}
+	for _, template := range s.transaction_templates {
+		switch {
+		case template.name == "":
+			add(severity_error, "", "the name of the transaction template can't be empty")
+		case templates[template.name]:
+			add(severity_error, "", "the transaction template ", template.name, " is repeated")
+		}
+		templates[template.name] = true
+		if len(template.lines) == 0 {
+			add(severity_error, "", "the transaction template ", template.name, " has no lines")
+		}
+		parameters := map[string]bool{}
+		for _, parameter := range template.parameters {
+			switch {
+			case !is_identifier(parameter.name):
+				add(severity_error, "", "the parameter [", parameter.name, "] of ", template.name, " should be a letter or _ then letters, digits or _")
+			case parameters[parameter.name]:
+				add(severity_error, "", "the parameter ", parameter.name, " of ", template.name, " is repeated")
+			}
+			if _, ok := line_reference(parameter.name); ok {
+				add(severity_error, "", "the parameter ", parameter.name, " of ", template.name, " is the name of a line")
+			}
+			if !IS_IN(parameter.kind, template_parameter_kinds[:]) {
+				add(severity_error, "", "the kind ", parameter.kind, " of ", parameter.name, " in ", template.name, " is not in ", template_parameter_kinds)
+			}
+			parameters[parameter.name] = true
+		}
+		for index, line := range template.lines {
+			where := fmt.Sprint(" in line", index+1, " of ", template.name)
+			lookup := func(name string) (decimal.Decimal, error) {
+				if parameter := template.parameter(name); parameter != nil && IS_IN(parameter.kind, []string{"value", "quantity", "percent"}) {
+					return decimal.NewFromInt(1), nil
+				}
+				if line_index, ok := line_reference(name); ok && line_index < index {
+					return decimal.NewFromInt(1), nil
+				}
+				return decimal.Zero, ErrInvalidConfig{fmt.Sprint(name, where, " is not a number parameter or a line before it")}
+			}
+			account := line.account
+			if name, ok := tree.codes[account]; ok {
+				account = name
+			}
+			parameter := template.parameter(line.account)
+			switch {
+			case parameter != nil && parameter.kind == "account":
+			case !s.is_account(account):
+				add(severity_error, line.account, line.account, where, " is not in the accounts")
+			case s.is_inactive(account):
+				add(severity_warning, account, account, where, " is deactivated so the entries that use it will fail")
+			}
+			if parameter := template.parameter(line.barcode); parameter != nil && parameter.kind != "barcode" {
+				add(severity_error, "", "the barcode ", line.barcode, where, " is a ", parameter.kind, " parameter")
+			}
+			switch line.method {
+			case "fixed":
+				if _, err := decimal.NewFromString(line.amount); err != nil {
+					add(severity_error, "", "the amount ", line.amount, where, " should be a number because the method is fixed")
+				}
+			case "percent_of_value":
+				if parameter := template.parameter(line.of); parameter == nil || parameter.kind != "value" {
+					add(severity_error, "", "[", line.of, "]", where, " should be a value parameter because the method is percent_of_value")
+				}
+			case "percent_of_line":
+				if line_index, ok := line_reference(line.of); !ok || line_index >= index {
+					add(severity_error, "", "[", line.of, "]", where, " should be a line before it like line1 because the method is percent_of_line")
+				}
+			case "formula":
+			default:
+				add(severity_error, "", "the method ", line.method, where, " is not in ", template_line_methods)
+				continue
+			}
+			if _, err := evaluate_expression(line.amount, lookup); err != nil {
+				add(severity_error, "", err)
+			}
+			if line.quantity == "" {
+				continue
+			}
+			if _, err := evaluate_expression(line.quantity, lookup); err != nil {
+				add(severity_error, "", err)
+			}
+		}
+	}
+	return violations
+}
+
+func is_identifier(name string) bool {
+	for index, r := range name {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || index > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// evaluate_expression calculates + - * / and the brackets in the order of math, the names are looked up by lookup.
+// the division by zero is zero like divide
+func evaluate_expression(text string, lookup func(string) (decimal.Decimal, error)) (decimal.Decimal, error) {
+	e := expression{text: text, lookup: lookup}
+	number, err := e.sum()
+	if err != nil {
+		return decimal.Zero, err
+	}
+	e.skip_spaces()
+	if e.position != len(e.text) {
+		return decimal.Zero, e.invalid()
+	}
+	return number, nil
+}
+
+type expression struct {
+	text     string
+	position int
+	lookup   func(string) (decimal.Decimal, error)
+}
+
+func (e *expression) sum() (decimal.Decimal, error) {
+	number, err := e.product()
+	for err == nil {
+		e.skip_spaces()
+		if e.position == len(e.text) || (e.text[e.position] != '+' && e.text[e.position] != '-') {
+			break
+		}
+		operator := e.text[e.position]
+		e.position++
+		var next decimal.Decimal
+		next, err = e.product()
+		if operator == '+' {
+			number = number.Add(next)
+		} else {
+			number = number.Sub(next)
+		}
+	}
+	return number, err
+}
+
+func (e *expression) product() (decimal.Decimal, error) {
+	number, err := e.factor()
+	for err == nil {
+		e.skip_spaces()
+		if e.position == len(e.text) || (e.text[e.position] != '*' && e.text[e.position] != '/') {
+			break
+		}
+		operator := e.text[e.position]
+		e.position++
+		var next decimal.Decimal
+		next, err = e.factor()
+		if operator == '*' {
+			number = number.Mul(next)
+		} else {
+			number = divide(number, next)
+		}
+	}
+	return number, err
+}
+
+func (e *expression) factor() (decimal.Decimal, error) {
+	e.skip_spaces()
+	if e.position == len(e.text) {
+		return decimal.Zero, e.invalid()
+	}
+	start := e.position
+	switch r := e.text[e.position]; {
+	case r == '-' || r == '+':
+		e.position++
+		number, err := e.factor()
+		if r == '-' {
+			number = number.Neg()
+		}
+		return number, err
+	case r == '(':
+		e.position++
+		number, err := e.sum()
+		if err != nil {
+			return decimal.Zero, err
+		}
+		e.skip_spaces()
+		if e.position == len(e.text) || e.text[e.position] != ')' {
+			return decimal.Zero, e.invalid()
+		}
+		e.position++
+		return number, nil
+	case r == '.' || r >= '0' && r <= '9':
+		for e.position < len(e.text) && (e.text[e.position] == '.' || e.text[e.position] >= '0' && e.text[e.position] <= '9') {
+			e.position++
+		}
+		number, err := decimal.NewFromString(e.text[start:e.position])
+		if err != nil {
+			return decimal.Zero, e.invalid()
+		}
+		return number, nil
+	}
+	for e.position < len(e.text) && is_identifier(e.text[start:e.position+1]) {
+		e.position++
+	}
+	if e.position == start {
+		return decimal.Zero, e.invalid()
+	}
+	return e.lookup(e.text[start:e.position])
+}
+
+func (e *expression) skip_spaces() {
+	for e.position < len(e.text) && e.text[e.position] == ' ' {
+		e.position++
+	}
+}
+
+func (e *expression) invalid() error {
+	return ErrInvalidConfig{fmt.Sprint("the formula [", e.text, "] is wrong at ", e.position+1)}
+}
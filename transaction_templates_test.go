@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// the formulas are calculated in the order of math and the wrong formulas are errors
+func TestEvaluateExpression(t *testing.T) {
+	names := map[string]decimal.Decimal{"quantity": number(3), "price": number(2.5), "line1": number(10), "_rate2": number(0.5)}
+	lookup := func(name string) (decimal.Decimal, error) {
+		if number, ok := names[name]; ok {
+			return number, nil
+		}
+		return decimal.Zero, ErrInvalidConfig{name + " is unknown"}
+	}
+	for text, expected := range map[string]float64{
+		"1":                        1,
+		" 1.25 ":                   1.25,
+		".5":                       0.5,
+		"1+2*3":                    7,
+		"(1+2)*3":                  9,
+		"10-4-3":                   3,
+		"12/4/3":                   1,
+		"2*3+4*5":                  26,
+		"-2*-3":                    6,
+		"-(1+2)":                   -3,
+		"+4":                       4,
+		"quantity*price-line1":     -2.5,
+		"line1*(1+_rate2)":         15,
+		"quantity * (price + 1)":   10.5,
+		"1/0":                      0,
+		"((2))":                    2,
+		"line1 - quantity*price/5": 8.5,
+	} {
+		actual, err := evaluate_expression(text, lookup)
+		if err != nil {
+			t.Errorf("[%s] is %v", text, err)
+			continue
+		}
+		if !actual.Equal(number(expected)) {
+			t.Errorf("[%s] is %s not %v", text, actual, expected)
+		}
+	}
+	for _, text := range []string{"", "1+", "*2", "(1+2", "1+2)", "1 2", "1..2", "price$", "nothing*2", "line1(2)", "2*()"} {
+		if _, err := evaluate_expression(text, lookup); err == nil {
+			t.Errorf("[%s] should be an error", text)
+		}
+	}
+}
+
+// the template makes the lines from its parameters and posts them in one entry, the inventory line takes its cost
+func TestTemplateEntry(t *testing.T) {
+	s := test_company(t, "memory", "")
+	s.transaction_templates = []transaction_template{
+		{"sell book", []template_parameter{{"quantity", "quantity"}, {"price", "value"}, {"tax_rate", "percent"}}, []template_line{
+			{"revenue of book", "", "formula", "quantity*price", "", ""},
+			{"tax", "", "percent_of_line", "tax_rate", "line1", ""},
+			{"cash", "", "formula", "line1+line2", "", ""},
+		}},
+		{"deliver book", []template_parameter{{"quantity", "quantity"}}, []template_line{
+			{"book", "", "formula", "-1", "", "-quantity"},
+			{"cost of book", "", "formula", "-line1", "", ""},
+		}},
+	}
+	s.initialize()
+	post(t, s, test_day, line("cash", 1000, 1000), line("capital", 1000, 1000))
+	post(t, s, test_day, line("book", 100, 10), line("cash", -100, -100))
+	entry, err := s.template_entry("sell book", map[string]string{"quantity": "4", "price": "25", "tax_rate": "14"}, true, test_day, "", "", "clerk")
+	check(t, err)
+	_, err = s.template_entry("deliver book", map[string]string{"quantity": "4"}, true, test_day, "", "", "clerk")
+	check(t, err)
+	for _, tag := range entry {
+		if tag.entry_number != entry[0].entry_number {
+			t.Errorf("the template is posted in the entries %d and %d", entry[0].entry_number, tag.entry_number)
+		}
+	}
+	expect_balance(t, s, "revenue of book", 100)
+	expect_balance(t, s, "tax", 14)
+	expect_balance(t, s, "cash", 1014)
+	expect_balance(t, s, "book", 60)
+	expect_balance(t, s, "cost of book", 40)
+	if _, err := s.template_entry("sell book", map[string]string{"quantity": "four", "price": "25", "tax_rate": "14"}, true, test_day, "", "", "clerk"); err == nil {
+		t.Error("the input that is not a number should be an error")
+	}
+	if _, err := s.template_entry("sell book", map[string]string{"quantity": "4", "price": "25"}, true, test_day, "", "", "clerk"); err == nil {
+		t.Error("the missing input should be an error")
+	}
+	if _, err := s.template_entry("nothing", nil, true, test_day, "", "", "clerk"); err != (ErrUnknownTemplate{"nothing"}) {
+		t.Errorf("the unknown template should be ErrUnknownTemplate not %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// violation is one problem that validate finds, the errors are the problems that make initialize or journal_entry fail,
+// the warnings are allowed but they are most likely a mistake
+type violation struct {
+	severity     string
+	account      string
+	entry_number int // 0 if the violation is not in the journal
+	reason       string
+}
+
+const (
+	severity_error   = "error"
+	severity_warning = "warning"
+)
+
+func (v violation) String() string {
+	switch {
+	case v.entry_number != 0:
+		return fmt.Sprint(v.severity, ": entry ", v.entry_number, ": ", v.reason)
+	case v.account != "":
+		return fmt.Sprint(v.severity, ": ", v.account, ": ", v.reason)
+	}
+	return fmt.Sprint(v.severity, ": ", v.reason)
+}
+
+// first_error returns the first violation that is an error, the warnings are ignored
+func first_error(violations []violation) error {
+	for _, v := range violations {
+		if v.severity == severity_error {
+			return ErrInvalidConfig{v.reason}
+		}
+	}
+	return nil
+}
+
+// validate returns all the problems in the config, the chart, the special accounts, the auto complete entries, the transaction templates,
+// the approval rules and the journal without stopping at the first one like initialize. the chart is read from the database if it has
+// one like load_accounts does, the database is opened if it is not opened yet and the journal is not checked if driver_name is empty
+func (s Financial_accounting) validate() ([]violation, error) {
+	if s.db == nil {
+		if s.DriverName == "" {
+			return s.config_violations(), nil
+		}
+		var err error
+		s.db, err = open_storage(s.DriverName, s.DataSourceName, s.Database_name)
+		if err != nil {
+			return nil, err
+		}
+		err = s.db.migrate(s.date_layout)
+		if err != nil {
+			return nil, err
+		}
+	}
+	accounts, err := s.db.accounts()
+	if err != nil {
+		return nil, err
+	}
+	if len(accounts) != 0 {
+		s.accounts = accounts
+	}
+	violations := s.config_violations()
+	s.tree, _ = build_account_tree(s.accounts)
+	s.inventory_accounts, _ = s.chart_violations()
+	ledger_violations, err := s.ledger_violations()
+	return append(violations, ledger_violations...), err
+}
+
+// ledger_violations checks the journal and the inventory against the chart, every entry is grouped by its entry number and date
+// and checked by check_debit_equal_credit like initialize checks it
+func (s Financial_accounting) ledger_violations() ([]violation, error) {
+	var violations []violation
+	inventory_accounts, err := s.db.inventory_accounts()
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range inventory_accounts {
+		if !IS_IN(a, s.inventory_accounts) {
+			violations = append(violations, violation{severity_error, a, 0, fmt.Sprint(a, " is in the inventory and it is not have fifo lifo wma on cost_flow_type field")})
+		}
+	}
+	journal, err := s.db.journal()
+	if err != nil {
+		return nil, err
+	}
+	tree := s.chart_tree()
+	unknown := map[int]bool{}
+	balances := map[string]decimal.Decimal{}
+	var accounts []string
+	for _, row := range journal {
+		if !s.is_account(row.account) {
+			if !unknown[row.entry_number] {
+				violations = append(violations, violation{severity_error, row.account, row.entry_number, ErrUnknownAccount{row.account}.Error()})
+			}
+			unknown[row.entry_number] = true
+			continue
+		}
+		if _, ok := balances[row.account]; !ok {
+			accounts = append(accounts, row.account)
+			// close_year posts to retained_earnings and it is the father of income_statement and dividends
+			if len(tree.children[row.account]) != 0 && row.account != s.retained_earnings {
+				violations = append(violations, violation{severity_warning, row.account, row.entry_number, fmt.Sprint(row.account, " has entries and it is the father of ", tree.children[row.account])})
+			}
+		}
+		balances[row.account] = balances[row.account].Add(row.value)
+	}
+	for _, one_entry := range group_by_entry_number(journal) {
+		entry_number := one_entry[0].entry_number
+		if unknown[entry_number] {
+			continue
+		}
+		var entry []Account_value_quantity_barcode
+		for _, row := range one_entry {
+			entry = append(entry, Account_value_quantity_barcode{Account: row.account, value: row.value})
+		}
+		_, _, err = s.check_debit_equal_credit(entry, false)
+		if err != nil {
+			violations = append(violations, violation{severity_error, "", entry_number, err.Error()})
+		}
+	}
+	for _, a := range accounts {
+		if balances[a].IsNegative() && !s.can_be_negative(a) {
+			violations = append(violations, violation{severity_warning, a, 0, fmt.Sprint("the balance of ", a, " is ", balances[a], " and it can't be negative")})
+		}
+	}
+	return violations, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func expect_violations(t *testing.T, violations []violation, expected []violation) {
+	t.Helper()
+	if len(violations) != len(expected) {
+		t.Fatalf("validate found %d violations not %d %v", len(violations), len(expected), violations)
+	}
+	for index, v := range expected {
+		got := violations[index]
+		if got.severity != v.severity || got.account != v.account || got.entry_number != v.entry_number || !strings.Contains(got.reason, v.reason) {
+			t.Errorf("violation %d is %v not %v", index, got, v)
+		}
+	}
+}
+
+// TestValidateConfig breaks the config in many ways and validate reports all of them without stopping at the first one
+func TestValidateConfig(t *testing.T) {
+	s := test_company(t, "", "")
+	s.sales = "sale"
+	s.accounts = append(s.accounts, account{false, "", "expenses", "rent", false, "", account_metadata{}}, account{false, "abc", "assets", "car", false, "", account_metadata{}})
+	s.accounts[index_of_account(s.accounts, "tax")].inactive = true
+	s.auto_complete_entries = [][]account_method_value_price{{{"cash", "", number(0), number(0)}, {"tax", "copy", number(0.1), number(0)}}}
+	s.Invoice_discounts_list = [][2]decimal.Decimal{{number(100), number(5)}, {number(50), number(2)}}
+	violations, err := s.validate()
+	check(t, err)
+	expect_violations(t, violations, []violation{
+		{severity_error, "", 0, "driver_name is empty"},
+		{severity_error, "sale", 0, "sales is sale and it is not in the accounts"},
+		{severity_error, "rent", 0, "rent is duplicated in the accounts"},
+		{severity_error, "car", 0, "abc for car is not in [fifo,lifo,wma,'']"},
+		{severity_error, "sale", 0, "ebitda should be one of the fathers of sale"},
+		{severity_error, "invoice_discount", 0, "invoice_discounts_list should be in ascending order"},
+		{severity_warning, "tax", 0, "tax in auto_complete_entries is deactivated"},
+	})
+	if err := first_error(violations); err == nil || err.Error() != "driver_name is empty" {
+		t.Errorf("the first error is %v", err)
+	}
+}
+
+// TestValidateJournal saves broken entries without the checks of journal_entry, every entry is checked like initialize
+// checks it so the compound entry that has more than one debit and more than one credit is reported even if it balances
+func TestValidateJournal(t *testing.T) {
+	s := test_company(t, "sqlite3", "")
+	s.initialize()
+	post(t, s, test_day, line("cash", 1000, 1000), line("capital", 1000, 1000))
+	for _, row := range []journal_tag{
+		{entry_number: 100, account: "cash", value: number(10)},
+		{entry_number: 100, account: "car", value: number(10)},
+		{entry_number: 101, account: "cash", value: number(10)},
+		{entry_number: 101, account: "capital", value: number(5)},
+		{entry_number: 102, account: "cash", value: number(10)},
+		{entry_number: 102, account: "rent", value: number(10)},
+		{entry_number: 102, account: "capital", value: number(10)},
+		{entry_number: 102, account: "tax", value: number(10)},
+		{entry_number: 103, account: "expenses", value: number(5)},
+		{entry_number: 103, account: "cash", value: number(-5)},
+		{entry_number: 104, account: "rent", value: number(-20)},
+		{entry_number: 104, account: "cash", value: number(20)},
+		// the adjusted entry balances at every date of it
+		{entry_number: 105, account: "prepaid rent", value: number(1), date: test_day.AddDate(0, 1, 0)},
+		{entry_number: 105, account: "cash", value: number(-1), date: test_day.AddDate(0, 1, 0)},
+		{entry_number: 105, account: "prepaid rent", value: number(1), date: test_day.AddDate(0, 2, 0)},
+		{entry_number: 105, account: "cash", value: number(-1), date: test_day.AddDate(0, 2, 0)},
+	} {
+		if row.date.IsZero() {
+			row.date = test_day
+		}
+		check(t, s.db.insert_journal(row))
+	}
+	violations, err := s.validate()
+	check(t, err)
+	expect_violations(t, violations, []violation{
+		{severity_error, "car", 100, ErrUnknownAccount{"car"}.Error()},
+		{severity_warning, "expenses", 103, "expenses has entries and it is the father of"},
+		{severity_error, "", 101, "5 not equal 0"},
+		{severity_error, "", 102, "should be one credit or one debit in the entry"},
+		{severity_warning, "rent", 0, "the balance of rent is -10"},
+	})
+}
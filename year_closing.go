@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// close_year posts the entries that make the balances of the accounts under income_statement and dividends zero and move them
+// to retained_earnings. they are dated end_date so the statements of the year still show its income and they are the first
+// entries of the next year, the statements of the years after it are the same because sum_values moves the balances of the
+// years before to retained_earnings anyway. every name has its own entries so the statements by name stay the same
+
+type year_closing struct {
+	id            int
+	end_date      time.Time
+	entry_numbers []int
+	date          time.Time
+	employee_name string
+	undone_by     string // the employee that undid the closing, empty if it is not undone
+	undo_date     time.Time
+}
+
+func (s Financial_accounting) close_year(end_date time.Time, employee_name string) (year_closing, error) {
+	var closing year_closing
+	err := s.db.transaction(func(tx storage) error {
+		err := tx.lock_ledger()
+		if err != nil {
+			return err
+		}
+		closings, err := tx.year_closings()
+		if err != nil {
+			return err
+		}
+		for _, c := range closings {
+			if c.undone_by == "" && !c.end_date.Before(end_date) {
+				return ErrInvalidSchedule{fmt.Sprint("the year ending ", c.end_date, " is closed, undo it before closing the year ending ", end_date)}
+			}
+			if c.id > closing.id {
+				closing.id = c.id
+			}
+		}
+		closing.id++
+		journal, err := tx.journal_between_dates(time.Time{}, end_date)
+		if err != nil {
+			return err
+		}
+		var names []string
+		balances := map[string][]Account_value_quantity_barcode{}
+		for _, tag := range journal {
+			if !s.is_father(s.income_statement, tag.account) && !s.is_father(s.dividends, tag.account) {
+				continue
+			}
+			if _, ok := balances[tag.name]; !ok {
+				names = append(names, tag.name)
+			}
+			balances[tag.name] = append(balances[tag.name], Account_value_quantity_barcode{Account: tag.account, value: tag.value, quantity: tag.quantity})
+		}
+		description := fmt.Sprint("(closing of the year ending ", end_date, " to ", s.retained_earnings, ")")
+		for _, name := range names {
+			// the accounts that are debited and the accounts that are credited are closed in two entries
+			// so every entry has one line on one of its sides
+			var sides [2][]Account_value_quantity_barcode
+			for _, balance := range group_by_account_and_barcode(balances[name]) {
+				if balance.value.IsZero() {
+					continue
+				}
+				line := Account_value_quantity_barcode{Account: balance.Account, value: balance.value.Neg(), quantity: balance.quantity.Neg()}
+				if line.quantity.IsZero() || line.quantity.IsNegative() != line.value.IsNegative() {
+					line.quantity = line.value
+				}
+				is_debit, err := s.is_debit(journal_tag{account: line.Account, value: line.value})
+				if err != nil {
+					return err
+				}
+				if is_debit {
+					sides[0] = append(sides[0], line)
+				} else {
+					sides[1] = append(sides[1], line)
+				}
+			}
+			for _, lines := range sides {
+				if len(lines) == 0 {
+					continue
+				}
+				var difference decimal.Decimal
+				for _, line := range lines {
+					is_credit, err := s.is_credit(line.Account)
+					if err != nil {
+						return err
+					}
+					if is_credit {
+						difference = difference.Sub(line.value)
+					} else {
+						difference = difference.Add(line.value)
+					}
+				}
+				lines = append(lines, Account_value_quantity_barcode{s.retained_earnings, difference, difference, ""})
+				entry, err := insert_to_journal_tag(lines, end_date, time.Time{}, description, name, employee_name)
+				if err != nil {
+					return err
+				}
+				err = s.check_periods(tx, entry, employee_name)
+				if err != nil {
+					return err
+				}
+				err = s.insert_to_database(tx, entry, true, true, true)
+				if err != nil {
+					return err
+				}
+				closing.entry_numbers = append(closing.entry_numbers, entry[0].entry_number)
+			}
+		}
+		if len(closing.entry_numbers) == 0 {
+			return ErrInvalidSchedule{fmt.Sprint("the accounts under ", s.income_statement, " and ", s.dividends, " have no balance before ", end_date)}
+		}
+		closing.end_date, closing.date, closing.employee_name = end_date, Now, employee_name
+		return tx.insert_year_closing(closing)
+	})
+	if err != nil {
+		return year_closing{}, err
+	}
+	return closing, nil
+}
+
+// undo_year_closing reverses all the entries of the last closing that is not undone on end_date, so the balances are back
+// as they were before it. the closing is kept with the employee that undid it
+func (s Financial_accounting) undo_year_closing(end_date time.Time, employee_name string) error {
+	return s.db.transaction(func(tx storage) error {
+		err := tx.lock_ledger()
+		if err != nil {
+			return err
+		}
+		closings, err := tx.year_closings()
+		if err != nil {
+			return err
+		}
+		var closing *year_closing
+		for index, c := range closings {
+			if c.undone_by != "" {
+				continue
+			}
+			if c.end_date.After(end_date) {
+				return ErrInvalidSchedule{fmt.Sprint("the year ending ", c.end_date, " is closed after it, undo it first")}
+			}
+			if c.end_date.Equal(end_date) {
+				closing = &closings[index]
+			}
+		}
+		if closing == nil {
+			return ErrInvalidSchedule{fmt.Sprint("the year ending ", end_date, " is not closed")}
+		}
+		for _, entry_number := range closing.entry_numbers {
+			entry, err := tx.journal_of_entry_number(uint(entry_number))
+			if err != nil {
+				return err
+			}
+			err = s.check_periods(tx, entry, employee_name)
+			if err != nil {
+				return err
+			}
+			var reverse []journal_tag
+			for _, tag := range entry {
+				err = tx.set_reverse(tag.id)
+				if err != nil {
+					return err
+				}
+				tag.description = fmt.Sprint("(reverse of the closing entry ", entry_number, " by ", employee_name, ")")
+				tag.value, tag.quantity, tag.foreign_value = tag.value.Neg(), tag.quantity.Neg(), tag.foreign_value.Neg()
+				tag.employee_name, tag.entry_date, tag.reverse = employee_name, Now, false
+				reverse = append(reverse, tag)
+			}
+			err = s.insert_to_database(tx, reverse, true, true, true)
+			if err != nil {
+				return err
+			}
+		}
+		return tx.set_year_closing_undone(closing.id, employee_name, Now)
+	})
+}
+
+func (s Financial_accounting) year_closings() ([]year_closing, error) {
+	return s.db.year_closings()
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+var year_end = test_day.AddDate(1, 0, 0)
+
+// year_company posts the income and the dividends of the year by two names, a has sales and dividends and b has rent
+func year_company(t *testing.T, driver string) Financial_accounting {
+	t.Helper()
+	s := test_company(t, driver, "")
+	s.period_managers = []string{"boss"}
+	s.initialize()
+	post(t, s, test_day, line("cash", 1000, 1000), line("capital", 1000, 1000))
+	for _, a := range []struct {
+		name  string
+		lines []Account_value_quantity_barcode
+	}{
+		{"a", []Account_value_quantity_barcode{line("cash", 300, 300), line("revenue of book", 300, 300)}},
+		{"b", []Account_value_quantity_barcode{line("rent", 100, 100), line("cash", -100, -100)}},
+		{"a", []Account_value_quantity_barcode{line("dividends", 50, 50), line("cash", -50, -50)}},
+	} {
+		_, err := s.journal_entry(a.lines, true, false, test_day.AddDate(0, 1, 0), time.Time{}, "", "", a.name, "clerk", nil)
+		check(t, err)
+	}
+	return s
+}
+
+// year_statements returns the ending balances of the year and of the month after it by account and name
+func year_statements(t *testing.T, s Financial_accounting) [2]map[string]map[string]string {
+	t.Helper()
+	var balances [2]map[string]map[string]string
+	for index, dates := range [][2]time.Time{{test_day.AddDate(0, 0, -1), year_end}, {year_end.AddDate(0, 0, 1), year_end.AddDate(0, 1, 0)}} {
+		statements, _, _, err := s.financial_statements(dates[0], dates[1], 1, nil, false)
+		check(t, err)
+		balances[index] = map[string]map[string]string{}
+		for account, names := range statements[0]["financial_statement"] {
+			balances[index][account] = map[string]string{}
+			for name, values := range names {
+				balances[index][account][name] = values["value"]["ending_balance"].String()
+			}
+		}
+	}
+	return balances
+}
+
+func expect_same_statements(t *testing.T, before, after [2]map[string]map[string]string) {
+	t.Helper()
+	for index := range before {
+		for account, names := range before[index] {
+			for name, balance := range names {
+				if after[index][account][name] != balance {
+					t.Errorf("the ending balance of %s of %q in the statement %d is %s not %s", account, name, index, after[index][account][name], balance)
+				}
+			}
+		}
+	}
+}
+
+// the closing zeroes the accounts under income_statement and dividends of every name into retained_earnings and the
+// statements of the year and of the year after it stay as sum_values makes them without the closing
+func TestCloseYear(t *testing.T) {
+	for _, driver := range []string{"memory", "sqlite3"} {
+		s := year_company(t, driver)
+		before := year_statements(t, s)
+		if before[1]["retained_earnings"]["a"] != "250" || before[1]["retained_earnings"]["b"] != "-100" {
+			t.Fatalf("%s: the retained_earnings of the next year is %v before the closing", driver, before[1]["retained_earnings"])
+		}
+		closing, err := s.close_year(year_end, "boss")
+		check(t, err)
+		if len(closing.entry_numbers) != 3 {
+			t.Errorf("%s: the closing has the entries %v, a has one debit entry and one credit entry and b has one", driver, closing.entry_numbers)
+		}
+		for _, entry_number := range closing.entry_numbers {
+			entry, err := s.db.journal_of_entry_number(uint(entry_number))
+			check(t, err)
+			for _, tag := range entry {
+				if !tag.date.Equal(year_end) || tag.name != "a" && tag.name != "b" {
+					t.Errorf("%s: the closing line %v should be on %s with the name of its entry", driver, tag, year_end)
+				}
+			}
+		}
+		expect_balance(t, s, "revenue of book", 0)
+		expect_balance(t, s, "rent", 0)
+		expect_balance(t, s, "dividends", 0)
+		expect_balance(t, s, "retained_earnings", 150)
+		expect_same_statements(t, before, year_statements(t, s))
+	}
+}
+
+// the undo reverses every entry of the closing or none of them
+func TestUndoYearClosing(t *testing.T) {
+	for _, driver := range []string{"memory", "sqlite3"} {
+		s := year_company(t, driver)
+		before := year_statements(t, s)
+		closing, err := s.close_year(year_end, "boss")
+		check(t, err)
+		check(t, s.lock_period(year_end, year_end.AddDate(0, 0, 1), "boss"))
+		if _, ok := s.undo_year_closing(year_end, "boss").(ErrClosedPeriod); !ok {
+			t.Errorf("%s: the closing in the locked period should not be undone", driver)
+		}
+		expect_balance(t, s, "retained_earnings", 150)
+		closings, err := s.year_closings()
+		check(t, err)
+		if len(closings) != 1 || closings[0].undone_by != "" {
+			t.Fatalf("%s: the closing is changed by the undo that failed %v", driver, closings)
+		}
+		check(t, s.reopen_period(year_end, year_end.AddDate(0, 0, 1), "boss", "undo the closing"))
+		check(t, s.undo_year_closing(year_end, "boss"))
+		for _, entry_number := range closing.entry_numbers {
+			entry, err := s.db.journal_of_entry_number(uint(entry_number))
+			check(t, err)
+			for _, tag := range entry {
+				if !tag.reverse {
+					t.Errorf("%s: the closing line %v is not reversed", driver, tag)
+				}
+			}
+		}
+		expect_balance(t, s, "revenue of book", 300)
+		expect_balance(t, s, "rent", 100)
+		expect_balance(t, s, "dividends", 50)
+		expect_balance(t, s, "retained_earnings", 0)
+		expect_same_statements(t, before, year_statements(t, s))
+		closings, err = s.year_closings()
+		check(t, err)
+		if len(closings) != 1 || closings[0].undone_by != "boss" {
+			t.Errorf("%s: the closing is not kept as undone by boss %v", driver, closings)
+		}
+		if err := s.undo_year_closing(year_end, "boss"); err == nil {
+			t.Errorf("%s: the closing is undone twice", driver)
+		}
+		// the year is closed again after the undo
+		_, err = s.close_year(year_end, "boss")
+		check(t, err)
+		expect_balance(t, s, "retained_earnings", 150)
+	}
+}
+
+// the earlier year can't be closed or undone while a later year is closed
+func TestCloseEarlierYear(t *testing.T) {
+	s := year_company(t, "memory")
+	post(t, s, year_end.AddDate(0, 1, 0), line("cash", 40, 40), line("revenue of book", 40, 40))
+	_, err := s.close_year(year_end.AddDate(1, 0, 0), "boss")
+	check(t, err)
+	if _, err := s.close_year(year_end, "boss"); err == nil {
+		t.Error("the earlier year should not be closed after the later year")
+	}
+	if _, err := s.close_year(year_end.AddDate(1, 0, 0), "boss"); err == nil {
+		t.Error("the year should not be closed twice")
+	}
+	check(t, s.undo_year_closing(year_end.AddDate(1, 0, 0), "boss"))
+	_, err = s.close_year(year_end, "boss")
+	check(t, err)
+	_, err = s.close_year(year_end.AddDate(1, 0, 0), "boss")
+	check(t, err)
+	if _, ok := s.undo_year_closing(year_end, "boss").(ErrInvalidSchedule); !ok {
+		t.Error("the earlier year should not be undone before the later year")
+	}
+	expect_balance(t, s, "revenue of book", 0)
+	expect_balance(t, s, "retained_earnings", 190)
+}